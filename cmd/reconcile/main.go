@@ -0,0 +1,59 @@
+// Command reconcile compares the photos table with the blob storage once and
+// reports, or with -dry-run=false fixes, orphan objects and dangling photos.
+// With -reset-acl it also makes every photo object private, which has to be
+// done once for objects uploaded while photos were public-read.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/kurochkinivan/Meet/config"
+	"github.com/kurochkinivan/Meet/internal/app"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", true, "only report the drift, don't fix it")
+	resetACL := flag.Bool("reset-acl", false, "make every photo object private")
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logrus.Info("loading config")
+	cfg := config.MustLoad()
+
+	logrus.Info("initializing app")
+	a, err := app.NewApp(ctx, cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to initialize app")
+	}
+
+	if *resetACL {
+		reset, err := a.ResetACLs(ctx)
+		if err != nil {
+			logrus.WithError(err).Error("acl reset finished with errors")
+		}
+		fmt.Printf("private objects\t%d\n", reset)
+	}
+
+	report, err := a.Reconcile(ctx, *dryRun)
+	if err != nil {
+		logrus.WithError(err).Error("reconciliation finished with errors")
+	}
+	if report == nil {
+		return
+	}
+
+	for _, key := range report.OrphanObjects {
+		fmt.Printf("orphan object\t%s\n", key)
+	}
+	for _, photoID := range report.DanglingPhotos {
+		fmt.Printf("dangling photo\t%d\n", photoID)
+	}
+	for _, key := range report.DanglingVariants {
+		fmt.Printf("dangling variant\t%s\n", key)
+	}
+}
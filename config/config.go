@@ -39,9 +39,87 @@ type Config struct {
 	} `yaml:"redis"`
 
 	S3 struct {
-		BucketName string `yaml:"bucket_name" env:"S3_BUCKET_NAME" env-required:"true"`
-		PhotoLimit int64  `yaml:"photo_limit" env:"S3_PHOTO_LIMIT" env-required:"true"`
+		BucketName      string        `yaml:"bucket_name" env:"S3_BUCKET_NAME" env-required:"true"`
+		Endpoint        string        `yaml:"endpoint" env:"S3_ENDPOINT"`
+		Region          string        `yaml:"region" env:"S3_REGION"`
+		UsePathStyle    bool          `yaml:"use_path_style" env:"S3_USE_PATH_STYLE"`
+		AccessKeyID     string        `yaml:"access_key_id" env:"S3_ACCESS_KEY_ID"`
+		SecretAccessKey string        `yaml:"secret_access_key" env:"S3_SECRET_ACCESS_KEY"`
+		URLTemplate     string        `yaml:"url_template" env:"S3_URL_TEMPLATE" env-default:"https://storage.yandexcloud.net/{bucket}/{key}"`
+		PhotoLimit      int64         `yaml:"photo_limit" env:"S3_PHOTO_LIMIT" env-required:"true"`
+		PresignExpiry   time.Duration `yaml:"presign_expiry" env:"S3_PRESIGN_EXPIRY" env-required:"true"`
+		URLExpiry       time.Duration `yaml:"url_expiry" env:"S3_URL_EXPIRY" env-required:"true"`
 	} `yaml:"s3"`
+
+	Storage struct {
+		Backend     string `yaml:"backend" env:"STORAGE_BACKEND" env-default:"s3"`
+		DiskRoot    string `yaml:"disk_root" env:"STORAGE_DISK_ROOT"`
+		DiskBaseURL string `yaml:"disk_base_url" env:"STORAGE_DISK_BASE_URL"`
+		DiskSecret  string `yaml:"disk_secret" env:"STORAGE_DISK_SECRET"`
+	} `yaml:"storage"`
+
+	Images struct {
+		MaxFileSize   int64 `yaml:"max_file_size" env:"IMAGES_MAX_FILE_SIZE" env-required:"true"`
+		MaxWidth      int   `yaml:"max_width" env:"IMAGES_MAX_WIDTH" env-required:"true"`
+		MaxHeight     int   `yaml:"max_height" env:"IMAGES_MAX_HEIGHT" env-required:"true"`
+		VariantWidths []int `yaml:"variant_widths" env:"IMAGES_VARIANT_WIDTHS" env-separator:"," env-default:"160,480,1080"`
+	} `yaml:"images"`
+
+	Processing struct {
+		Workers      int           `yaml:"workers" env:"PROCESSING_WORKERS" env-required:"true"`
+		PollInterval time.Duration `yaml:"poll_interval" env:"PROCESSING_POLL_INTERVAL" env-required:"true"`
+		MaxAttempts  int           `yaml:"max_attempts" env:"PROCESSING_MAX_ATTEMPTS" env-required:"true"`
+		StaleAfter   time.Duration `yaml:"stale_after" env:"PROCESSING_STALE_AFTER" env-required:"true"`
+	} `yaml:"processing"`
+
+	Reconcile struct {
+		Interval time.Duration `yaml:"interval" env:"RECONCILE_INTERVAL"`
+		MinAge   time.Duration `yaml:"min_age" env:"RECONCILE_MIN_AGE" env-default:"1h"`
+		DryRun   bool          `yaml:"dry_run" env:"RECONCILE_DRY_RUN"`
+	} `yaml:"reconcile"`
+
+	Deletion struct {
+		GracePeriod  time.Duration `yaml:"grace_period" env:"DELETION_GRACE_PERIOD" env-required:"true"`
+		PollInterval time.Duration `yaml:"poll_interval" env:"DELETION_POLL_INTERVAL" env-required:"true"`
+		BatchSize    uint64        `yaml:"batch_size" env:"DELETION_BATCH_SIZE" env-required:"true"`
+	} `yaml:"deletion"`
+
+	Idempotency struct {
+		Window  time.Duration `yaml:"window" env:"IDEMPOTENCY_WINDOW" env-required:"true"`
+		LockTTL time.Duration `yaml:"lock_ttl" env:"IDEMPOTENCY_LOCK_TTL" env-required:"true"`
+	} `yaml:"idempotency"`
+
+	Push struct {
+		Sender        string        `yaml:"sender" env:"PUSH_SENDER" env-required:"true"`
+		FilePath      string        `yaml:"file_path" env:"PUSH_FILE_PATH"`
+		Workers       int           `yaml:"workers" env:"PUSH_WORKERS" env-required:"true"`
+		QueueSize     int           `yaml:"queue_size" env:"PUSH_QUEUE_SIZE" env-required:"true"`
+		MaxRetries    int           `yaml:"max_retries" env:"PUSH_MAX_RETRIES" env-required:"true"`
+		RetryInterval time.Duration `yaml:"retry_interval" env:"PUSH_RETRY_INTERVAL" env-required:"true"`
+	} `yaml:"push"`
+
+	Outbox struct {
+		PollInterval   time.Duration `yaml:"poll_interval" env:"OUTBOX_POLL_INTERVAL" env-required:"true"`
+		BatchSize      uint64        `yaml:"batch_size" env:"OUTBOX_BATCH_SIZE" env-required:"true"`
+		RedisStream    string        `yaml:"redis_stream" env:"OUTBOX_REDIS_STREAM"`
+		WebhookURL     string        `yaml:"webhook_url" env:"OUTBOX_WEBHOOK_URL"`
+		WebhookSecret  string        `yaml:"webhook_secret" env:"OUTBOX_WEBHOOK_SECRET"`
+		WebhookTimeout time.Duration `yaml:"webhook_timeout" env:"OUTBOX_WEBHOOK_TIMEOUT" env-default:"5s"`
+		ClaimTimeout   time.Duration `yaml:"claim_timeout" env:"OUTBOX_CLAIM_TIMEOUT" env-default:"5m"`
+		MaxAttempts    int           `yaml:"max_attempts" env:"OUTBOX_MAX_ATTEMPTS" env-default:"10"`
+		RetryBase      time.Duration `yaml:"retry_base" env:"OUTBOX_RETRY_BASE" env-default:"5s"`
+		RetryMax       time.Duration `yaml:"retry_max" env:"OUTBOX_RETRY_MAX" env-default:"1h"`
+	} `yaml:"outbox"`
+
+	Admin struct {
+		Token string `yaml:"token" env:"ADMIN_TOKEN" env-required:"true"`
+	} `yaml:"admin"`
+
+	Moderation struct {
+		AutoHideDuration  time.Duration `yaml:"auto_hide_duration" env:"MODERATION_AUTO_HIDE_DURATION" env-required:"true"`
+		AutoHideReporters int           `yaml:"auto_hide_reporters" env:"MODERATION_AUTO_HIDE_REPORTERS" env-default:"3"`
+		DuplicateDistance int           `yaml:"duplicate_distance" env:"MODERATION_DUPLICATE_DISTANCE" env-required:"true"`
+	} `yaml:"moderation"`
 }
 
 func MustLoad() *Config {
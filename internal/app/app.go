@@ -6,10 +6,17 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/kurochkinivan/Meet/config"
+	"github.com/kurochkinivan/Meet/internal/controller/http/admin"
 	v1 "github.com/kurochkinivan/Meet/internal/controller/http/v1"
+	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/kurochkinivan/Meet/internal/external/push"
+	"github.com/kurochkinivan/Meet/internal/external/webhook"
 	"github.com/kurochkinivan/Meet/internal/usecase"
+	"github.com/kurochkinivan/Meet/internal/usecase/repository/disk"
 	"github.com/kurochkinivan/Meet/internal/usecase/repository/pg"
 	"github.com/kurochkinivan/Meet/internal/usecase/repository/redis"
 	"github.com/kurochkinivan/Meet/internal/usecase/repository/s3"
@@ -20,9 +27,14 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+// multipartOverhead is more than the part headers and boundaries of an upload
+// ever take.
+const multipartOverhead = 1 << 20
+
 type App struct {
-	cfg    *config.Config
-	server *http.Server
+	cfg      *config.Config
+	server   *http.Server
+	usecases *usecase.UseCases
 }
 
 func NewApp(ctx context.Context, cfg *config.Config) (*App, error) {
@@ -54,19 +66,48 @@ func NewApp(ctx context.Context, cfg *config.Config) (*App, error) {
 		return nil, fmt.Errorf("failed to connect to redis: %w", err)
 	}
 
-	logrus.WithField("bucketName", cfg.S3.BucketName).Info("connecting to s3...")
-	clientS3, err := s3client.NewClient(ctx)
+	pgRepositories := pg.NewRepositories(clientPSQL)
+	redisRepositories := redis.NewRepositories(clientRedis, cfg.Redis.LFUCapacity, cfg.Redis.Expiration, cfg.Outbox.RedisStream)
+
+	logrus.WithField("backend", cfg.Storage.Backend).Info("setting up photo storage")
+	photoBlobs, err := newPhotoBlobStorage(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+		return nil, err
 	}
 
-	pgRepositories := pg.NewRepositories(clientPSQL)
-	redisRepositories := redis.NewRepositories(clientRedis, cfg.Redis.LFUCapacity, cfg.Redis.Expiration)
-	s3Repositories := s3.NewRepositories(clientS3, cfg.S3.BucketName)
+	logrus.WithField("sender", cfg.Push.Sender).Info("setting up push sender")
+	pushSender, err := newPushSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	eventSinks := make([]usecase.EventSink, 0)
+	if cfg.Outbox.RedisStream != "" {
+		logrus.WithField("stream", cfg.Outbox.RedisStream).Info("publishing outbox events to redis stream")
+		eventSinks = append(eventSinks, redisRepositories.EventRepository)
+	}
+	if cfg.Outbox.WebhookURL != "" {
+		logrus.WithField("url", cfg.Outbox.WebhookURL).Info("publishing outbox events to webhook")
+		eventSinks = append(eventSinks, webhook.NewSink(cfg.Outbox.WebhookURL, cfg.Outbox.WebhookSecret, cfg.Outbox.WebhookTimeout))
+	}
+
+	usecases := usecase.NewUseCases(cfg, pgRepositories, photoBlobs, redisRepositories, pushSender, eventSinks...)
 
-	usecases := usecase.NewUseCases(cfg, pgRepositories, s3Repositories, redisRepositories)
+	// the largest request is an upload of every photo a user may have at the
+	// size limit, plus room for the multipart framing
+	maxBodySize := cfg.S3.PhotoLimit*cfg.Images.MaxFileSize + multipartOverhead
 
-	handler := v1.NewHandler(usecases, cfg.HTTP.BytesLimit, cfg.HTTP.MaxLimit)
+	handler := http.NewServeMux()
+	handler.Handle("/admin/", admin.NewHandler(usecases, cfg.HTTP.BytesLimit, cfg.Admin.Token))
+	handler.Handle("/", v1.NewHandler(usecases, cfg.HTTP.BytesLimit, cfg.HTTP.MaxLimit, maxBodySize))
+	if files, ok := photoBlobs.(*disk.PhotoRepository); ok {
+		baseURL, err := url.Parse(cfg.Storage.DiskBaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse disk base url: %w", err)
+		}
+		prefix := strings.TrimSuffix(baseURL.Path, "/")
+		handler.Handle(prefix+"/", http.StripPrefix(prefix, files))
+	}
 
 	logrus.WithFields(logrus.Fields{
 		"host":          cfg.HTTP.Host,
@@ -87,11 +128,63 @@ func NewApp(ctx context.Context, cfg *config.Config) (*App, error) {
 	}
 
 	return &App{
-		server: server,
-		cfg:    cfg,
+		server:   server,
+		cfg:      cfg,
+		usecases: usecases,
 	}, nil
 }
 
+func newPhotoBlobStorage(ctx context.Context, cfg *config.Config) (usecase.PhotoBlobStorage, error) {
+	switch cfg.Storage.Backend {
+	case "s3":
+		logrus.WithFields(logrus.Fields{
+			"bucketName": cfg.S3.BucketName,
+			"endpoint":   cfg.S3.Endpoint,
+			"region":     cfg.S3.Region,
+		}).Info("connecting to s3...")
+		clientS3, err := s3client.NewClient(ctx, &s3client.S3Config{
+			Endpoint:        cfg.S3.Endpoint,
+			Region:          cfg.S3.Region,
+			UsePathStyle:    cfg.S3.UsePathStyle,
+			AccessKeyID:     cfg.S3.AccessKeyID,
+			SecretAccessKey: cfg.S3.SecretAccessKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create s3 client: %w", err)
+		}
+		return s3.NewRepositories(clientS3, cfg.S3.BucketName, cfg.S3.URLTemplate, cfg.S3.PresignExpiry, cfg.S3.URLExpiry).PhotoRepository, nil
+	case "disk":
+		if cfg.Storage.DiskRoot == "" || cfg.Storage.DiskBaseURL == "" || cfg.Storage.DiskSecret == "" {
+			return nil, errors.New("disk storage needs disk_root, disk_base_url and disk_secret")
+		}
+		// the files are served by this server under the path of the base url,
+		// it must not take over the api routes
+		baseURL, err := url.Parse(cfg.Storage.DiskBaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse disk base url: %w", err)
+		}
+		root, _, _ := strings.Cut(strings.Trim(baseURL.Path, "/"), "/")
+		if root == "" || root == "v1" || root == "admin" {
+			return nil, fmt.Errorf("disk_base_url %q needs a path outside of the api, e.g. /files", cfg.Storage.DiskBaseURL)
+		}
+		logrus.WithField("root", cfg.Storage.DiskRoot).Info("storing photos on disk")
+		return disk.NewPhotoRepository(cfg.Storage.DiskRoot, cfg.Storage.DiskBaseURL, cfg.Storage.DiskSecret, cfg.S3.PresignExpiry, cfg.S3.URLExpiry), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+}
+
+func newPushSender(cfg *config.Config) (usecase.PushSender, error) {
+	switch cfg.Push.Sender {
+	case "log":
+		return push.NewLogSender(), nil
+	case "file":
+		return push.NewFileSender(cfg.Push.FilePath), nil
+	default:
+		return nil, fmt.Errorf("unknown push sender %q", cfg.Push.Sender)
+	}
+}
+
 func (a *App) Run(ctx context.Context) error {
 	grp, ctx := errgroup.WithContext(ctx)
 
@@ -99,9 +192,41 @@ func (a *App) Run(ctx context.Context) error {
 		return a.startHTTP(ctx)
 	})
 
+	grp.Go(func() error {
+		return a.usecases.PushUseCase.Run(ctx)
+	})
+
+	grp.Go(func() error {
+		return a.usecases.OutboxRelay.Run(ctx)
+	})
+
+	grp.Go(func() error {
+		return a.usecases.PhotoProcessor.Run(ctx)
+	})
+
+	grp.Go(func() error {
+		return a.usecases.AccountEraser.Run(ctx)
+	})
+
+	if a.cfg.Reconcile.Interval > 0 {
+		grp.Go(func() error {
+			return a.usecases.Reconciler.Run(ctx)
+		})
+	}
+
 	return grp.Wait()
 }
 
+// Reconcile runs a single reconciliation of the photos with the blob storage.
+func (a *App) Reconcile(ctx context.Context, dryRun bool) (*entity.ReconcileReport, error) {
+	return a.usecases.Reconciler.Reconcile(ctx, dryRun)
+}
+
+// ResetACLs makes every photo object in the blob storage private.
+func (a *App) ResetACLs(ctx context.Context) (int, error) {
+	return a.usecases.Reconciler.ResetACLs(ctx)
+}
+
 func (a *App) startHTTP(ctx context.Context) error {
 	err := a.server.ListenAndServe()
 	if err != nil {
@@ -11,7 +11,27 @@ var (
 
 // service error
 var (
-	ErrUserExists = errors.New("user with this phone already exists")
+	ErrUserExists              = errors.New("user with this phone already exists")
+	ErrUserNotFound            = errors.New("user not found")
+	ErrDeletionNotScheduled    = errors.New("account deletion is not scheduled or can't be canceled anymore")
+	ErrPhotoNotFound           = errors.New("photo not found")
+	ErrUploadNotFound          = errors.New("upload not found")
+	ErrUploadConfirmed         = errors.New("upload is already confirmed")
+	ErrPhotoLimitExceeded      = errors.New("photo limit exceeded")
+	ErrSelfBlock               = errors.New("user can't block themselves")
+	ErrInvalidReport           = errors.New("invalid report")
+	ErrReportTargetNotFound    = errors.New("report target not found")
+	ErrUnauthenticated         = errors.New("user is not authenticated")
+	ErrForbidden               = errors.New("user has no permission for this action")
+	ErrUserBanned              = errors.New("user is banned")
+	ErrInvalidBan              = errors.New("invalid ban")
+	ErrInvalidPhotoReview      = errors.New("invalid photo review")
+	ErrUnknownNotificationType = errors.New("unknown notification type")
+	ErrUnknownPlatform         = errors.New("unknown device platform")
+	ErrInvalidDeviceToken      = errors.New("device token is invalid or unregistered")
+	ErrInvalidIdempotencyKey   = errors.New("invalid idempotency key")
+	ErrRequestInFlight         = errors.New("request with this idempotency key is still in progress")
+	ErrIdempotencyKeyReused    = errors.New("idempotency key was used for a different request")
 )
 
 // transport error
@@ -0,0 +1,107 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 100
+)
+
+type appHandler func(http.ResponseWriter, *http.Request, httprouter.Params) error
+
+func errorHandler(f appHandler) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		err := f(w, r, p)
+		if err != nil {
+			http.Error(w, err.Error(), apperr.HTTPStatus(err))
+		}
+	}
+}
+
+type RoleChecker interface {
+	IsAdmin(ctx context.Context, userID string) (bool, error)
+}
+
+// Authorizer lets through only callers that present the admin token and act
+// as a user with the admin role.
+type Authorizer struct {
+	RoleChecker
+	token []byte
+}
+
+func NewAuthorizer(checker RoleChecker, token string) *Authorizer {
+	return &Authorizer{
+		RoleChecker: checker,
+		token:       []byte(token),
+	}
+}
+
+type adminKey struct{}
+
+// TODO: take the caller from the jwt once auth middleware is ready. Until then
+// the header only says which admin acts, the token is what grants access.
+const adminHeader = "X-User-ID"
+
+func (a *Authorizer) RequireAdmin(f appHandler) httprouter.Handle {
+	return errorHandler(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || len(a.token) == 0 || subtle.ConstantTimeCompare([]byte(token), a.token) != 1 {
+			return apperr.WithHTTPStatus(apperr.ErrUnauthenticated, http.StatusUnauthorized)
+		}
+
+		userID := r.Header.Get(adminHeader)
+		if userID == "" {
+			return apperr.WithHTTPStatus(apperr.ErrUnauthenticated, http.StatusUnauthorized)
+		}
+
+		ok, err := a.RoleChecker.IsAdmin(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return apperr.WithHTTPStatus(apperr.ErrForbidden, http.StatusForbidden)
+		}
+
+		ctx := context.WithValue(r.Context(), adminKey{}, userID)
+		return f(w, r.WithContext(ctx), p)
+	})
+}
+
+func adminID(r *http.Request) string {
+	id, _ := r.Context().Value(adminKey{}).(string)
+	return id
+}
+
+func parseUintQuery(value string, fallback uint64) (uint64, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	return strconv.ParseUint(value, 10, 64)
+}
+
+func parsePagination(r *http.Request) (limit uint64, offset uint64, err error) {
+	query := r.URL.Query()
+
+	limit, err = parseUintQuery(query.Get("limit"), defaultLimit)
+	if err != nil {
+		return 0, 0, apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+
+	offset, err = parseUintQuery(query.Get("offset"), 0)
+	if err != nil {
+		return 0, 0, apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+
+	return min(limit, maxLimit), offset, nil
+}
@@ -0,0 +1,199 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+type PhotoUseCase interface {
+	GetPhotoQueue(ctx context.Context, status entity.PhotoStatus, limit, offset uint64) ([]*entity.Photo, error)
+	ReviewPhoto(ctx context.Context, adminID string, photoID string, status entity.PhotoStatus, reason string) error
+	DeletePhoto(ctx context.Context, adminID string, photoID string) error
+	GetDuplicateClusters(ctx context.Context, limit, offset uint64) ([]*entity.PhotoCluster, error)
+}
+
+type PhotoHandler struct {
+	PhotoUseCase
+	bytesLimit int64
+}
+
+func NewPhotoHandler(bytesLimit int64, photoUseCase PhotoUseCase) Handler {
+	return &PhotoHandler{
+		PhotoUseCase: photoUseCase,
+		bytesLimit:   bytesLimit,
+	}
+}
+
+func (h *PhotoHandler) Register(r *httprouter.Router, auth *Authorizer) {
+	r.GET("/admin/v1/photos", auth.RequireAdmin(h.getPhotoQueue))
+	r.GET("/admin/v1/photos/duplicates", auth.RequireAdmin(h.getDuplicateClusters))
+	r.PATCH("/admin/v1/photos/:photo_id", auth.RequireAdmin(h.reviewPhoto))
+	r.DELETE("/admin/v1/photos/:photo_id", auth.RequireAdmin(h.deletePhoto))
+}
+
+type (
+	photoResponse struct {
+		ID              int64      `json:"id"`
+		UserID          uuid.UUID  `json:"user_id"`
+		URL             string     `json:"url"`
+		VariantURLs     []string   `json:"variant_urls"`
+		Status          string     `json:"status"`
+		RejectionReason string     `json:"rejection_reason,omitempty"`
+		CreatedAt       time.Time  `json:"created_at"`
+		HiddenUntil     *time.Time `json:"hidden_until,omitempty"`
+		DuplicateOf     *int64     `json:"duplicate_of,omitempty"`
+	}
+
+	getPhotoQueueResponse struct {
+		Photos []photoResponse `json:"photos"`
+	}
+)
+
+func (h *PhotoHandler) getPhotoQueue(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	status := entity.PhotoStatus(r.URL.Query().Get("status"))
+	if status == "" {
+		status = entity.PhotoStatusPending
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		return err
+	}
+
+	photos, err := h.PhotoUseCase.GetPhotoQueue(r.Context(), status, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	resp := getPhotoQueueResponse{
+		Photos: make([]photoResponse, 0, len(photos)),
+	}
+	for _, photo := range photos {
+		resp.Photos = append(resp.Photos, toPhotoResponse(photo))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+type (
+	photoClusterResponse struct {
+		ID     int64           `json:"id"`
+		Photos []photoResponse `json:"photos"`
+	}
+
+	getDuplicateClustersResponse struct {
+		Clusters []photoClusterResponse `json:"clusters"`
+	}
+)
+
+func (h *PhotoHandler) getDuplicateClusters(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		return err
+	}
+
+	clusters, err := h.PhotoUseCase.GetDuplicateClusters(r.Context(), limit, offset)
+	if err != nil {
+		return err
+	}
+
+	resp := getDuplicateClustersResponse{
+		Clusters: make([]photoClusterResponse, 0, len(clusters)),
+	}
+	for _, cluster := range clusters {
+		photos := make([]photoResponse, 0, len(cluster.Photos))
+		for _, photo := range cluster.Photos {
+			photos = append(photos, toPhotoResponse(photo))
+		}
+
+		resp.Clusters = append(resp.Clusters, photoClusterResponse{
+			ID:     cluster.ID,
+			Photos: photos,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+type (
+	reviewPhotoReq struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+)
+
+func (h *PhotoHandler) reviewPhoto(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	photoID := p.ByName("photo_id")
+
+	var req reviewPhotoReq
+	err := json.NewDecoder(io.LimitReader(r.Body, h.bytesLimit)).Decode(&req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return apperr.WithHTTPStatus(apperr.ErrEmptyBody, http.StatusBadRequest)
+		}
+		return apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+	defer r.Body.Close()
+
+	err = h.PhotoUseCase.ReviewPhoto(r.Context(), adminID(r), photoID, entity.PhotoStatus(req.Status), req.Reason)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *PhotoHandler) deletePhoto(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	photoID := p.ByName("photo_id")
+
+	err := h.PhotoUseCase.DeletePhoto(r.Context(), adminID(r), photoID)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func toPhotoResponse(photo *entity.Photo) photoResponse {
+	variantURLs := make([]string, 0, len(photo.Variants))
+	for _, variant := range photo.Variants {
+		variantURLs = append(variantURLs, variant.URL)
+	}
+
+	return photoResponse{
+		ID:              photo.ID,
+		UserID:          photo.UserID,
+		URL:             photo.URL,
+		VariantURLs:     variantURLs,
+		Status:          string(photo.Status),
+		RejectionReason: photo.RejectionReason,
+		CreatedAt:       photo.CreatedAt,
+		HiddenUntil:     photo.HiddenUntil,
+		DuplicateOf:     photo.DuplicateOf,
+	}
+}
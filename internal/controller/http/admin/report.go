@@ -0,0 +1,137 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+type ReportUseCase interface {
+	GetReportQueue(ctx context.Context, status entity.ReportStatus, limit, offset uint64) ([]*entity.Report, error)
+	UpdateReportStatus(ctx context.Context, adminID string, reportID string, status entity.ReportStatus) error
+}
+
+type ReportHandler struct {
+	ReportUseCase
+	bytesLimit int64
+}
+
+func NewReportHandler(bytesLimit int64, reportUseCase ReportUseCase) Handler {
+	return &ReportHandler{
+		ReportUseCase: reportUseCase,
+		bytesLimit:    bytesLimit,
+	}
+}
+
+func (h *ReportHandler) Register(r *httprouter.Router, auth *Authorizer) {
+	r.GET("/admin/v1/reports", auth.RequireAdmin(h.getReportQueue))
+	r.PATCH("/admin/v1/reports/:report_id", auth.RequireAdmin(h.updateReportStatus))
+}
+
+type (
+	reportResponse struct {
+		ID          int64      `json:"id"`
+		ReporterID  *uuid.UUID `json:"reporter_id,omitempty"`
+		TargetType  string     `json:"target_type"`
+		TargetID    string     `json:"target_id"`
+		Reason      string     `json:"reason"`
+		Comment     string     `json:"comment"`
+		Status      string     `json:"status"`
+		TargetCount int        `json:"target_count"`
+		CreatedAt   time.Time  `json:"created_at"`
+		UpdatedAt   time.Time  `json:"updated_at"`
+	}
+
+	getReportQueueResponse struct {
+		Reports []reportResponse `json:"reports"`
+	}
+)
+
+func (h *ReportHandler) getReportQueue(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	status := entity.ReportStatus(r.URL.Query().Get("status"))
+	if status == "" {
+		status = entity.ReportStatusOpen
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		return err
+	}
+
+	reports, err := h.ReportUseCase.GetReportQueue(r.Context(), status, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(getReportQueueResponse{
+		Reports: toReportResponses(reports),
+	})
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+type (
+	updateReportStatusReq struct {
+		Status string `json:"status"`
+	}
+)
+
+func (h *ReportHandler) updateReportStatus(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	reportID := p.ByName("report_id")
+
+	var req updateReportStatusReq
+	err := json.NewDecoder(io.LimitReader(r.Body, h.bytesLimit)).Decode(&req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return apperr.WithHTTPStatus(apperr.ErrEmptyBody, http.StatusBadRequest)
+		}
+		return apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+	defer r.Body.Close()
+
+	err = h.ReportUseCase.UpdateReportStatus(r.Context(), adminID(r), reportID, entity.ReportStatus(req.Status))
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func toReportResponses(reports []*entity.Report) []reportResponse {
+	resp := make([]reportResponse, 0, len(reports))
+	for _, report := range reports {
+		var reporterID *uuid.UUID
+		if report.ReporterID.Valid {
+			reporterID = &report.ReporterID.UUID
+		}
+
+		resp = append(resp, reportResponse{
+			ID:          report.ID,
+			ReporterID:  reporterID,
+			TargetType:  string(report.TargetType),
+			TargetID:    report.TargetID,
+			Reason:      string(report.Reason),
+			Comment:     report.Comment,
+			Status:      string(report.Status),
+			TargetCount: report.TargetCount,
+			CreatedAt:   report.CreatedAt,
+			UpdatedAt:   report.UpdatedAt,
+		})
+	}
+
+	return resp
+}
@@ -0,0 +1,29 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kurochkinivan/Meet/internal/usecase"
+)
+
+type Handler interface {
+	Register(r *httprouter.Router, auth *Authorizer)
+}
+
+func NewHandler(usecases *usecase.UseCases, bytesLimit int64, token string) http.Handler {
+	r := httprouter.New()
+
+	auth := NewAuthorizer(usecases.AdminUseCase, token)
+
+	userHandler := NewUserHandler(bytesLimit, usecases.AdminUseCase)
+	userHandler.Register(r, auth)
+
+	photoHandler := NewPhotoHandler(bytesLimit, usecases.AdminUseCase)
+	photoHandler.Register(r, auth)
+
+	reportHandler := NewReportHandler(bytesLimit, usecases.AdminUseCase)
+	reportHandler.Register(r, auth)
+
+	return r
+}
@@ -0,0 +1,209 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+type UserUseCase interface {
+	SearchUsers(ctx context.Context, query string, limit, offset uint64) ([]*entity.User, error)
+	GetUserProfile(ctx context.Context, userID string) (*entity.UserProfile, error)
+	BanUser(ctx context.Context, adminID string, userID string, kind entity.BanKind, reason string, expiresAt *time.Time) error
+	UnbanUser(ctx context.Context, adminID string, userID string) error
+}
+
+type UserHandler struct {
+	UserUseCase
+	bytesLimit int64
+}
+
+func NewUserHandler(bytesLimit int64, userUseCase UserUseCase) Handler {
+	return &UserHandler{
+		UserUseCase: userUseCase,
+		bytesLimit:  bytesLimit,
+	}
+}
+
+func (h *UserHandler) Register(r *httprouter.Router, auth *Authorizer) {
+	r.GET("/admin/v1/users", auth.RequireAdmin(h.searchUsers))
+	r.GET("/admin/v1/users/:id", auth.RequireAdmin(h.getUserProfile))
+	r.POST("/admin/v1/users/:id/ban", auth.RequireAdmin(h.banUser))
+	r.DELETE("/admin/v1/users/:id/ban", auth.RequireAdmin(h.unbanUser))
+}
+
+type (
+	userResponse struct {
+		UUID        uuid.UUID  `json:"uuid"`
+		Name        string     `json:"name"`
+		Birthday    time.Time  `json:"birthday"`
+		Sex         string     `json:"sex"`
+		Phone       string     `json:"phone"`
+		Role        string     `json:"role"`
+		CreatedAt   time.Time  `json:"created_at"`
+		HiddenUntil *time.Time `json:"hidden_until,omitempty"`
+	}
+
+	searchUsersResponse struct {
+		Users []userResponse `json:"users"`
+	}
+)
+
+func (h *UserHandler) searchUsers(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	query := r.URL.Query().Get("query")
+	if query == "" {
+		return apperr.WithHTTPStatus(errors.New("query parameter is required"), http.StatusBadRequest)
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		return err
+	}
+
+	users, err := h.UserUseCase.SearchUsers(r.Context(), query, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	resp := searchUsersResponse{
+		Users: make([]userResponse, 0, len(users)),
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, toUserResponse(user))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+type (
+	banResponse struct {
+		Kind      string     `json:"kind"`
+		Reason    string     `json:"reason"`
+		BannedBy  *uuid.UUID `json:"banned_by,omitempty"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+		CreatedAt time.Time  `json:"created_at"`
+	}
+
+	getUserProfileResponse struct {
+		User    userResponse     `json:"user"`
+		Photos  []photoResponse  `json:"photos"`
+		Reports []reportResponse `json:"reports"`
+		Ban     *banResponse     `json:"ban,omitempty"`
+	}
+)
+
+func (h *UserHandler) getUserProfile(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	profile, err := h.UserUseCase.GetUserProfile(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	resp := getUserProfileResponse{
+		User:    toUserResponse(profile.User),
+		Photos:  make([]photoResponse, 0, len(profile.Photos)),
+		Reports: toReportResponses(profile.Reports),
+	}
+	for _, photo := range profile.Photos {
+		resp.Photos = append(resp.Photos, toPhotoResponse(photo))
+	}
+	if profile.Ban != nil {
+		var bannedBy *uuid.UUID
+		if profile.Ban.BannedBy.Valid {
+			bannedBy = &profile.Ban.BannedBy.UUID
+		}
+
+		resp.Ban = &banResponse{
+			Kind:      string(profile.Ban.Kind),
+			Reason:    profile.Ban.Reason,
+			BannedBy:  bannedBy,
+			ExpiresAt: profile.Ban.ExpiresAt,
+			CreatedAt: profile.Ban.CreatedAt,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+type (
+	banUserReq struct {
+		Kind      string     `json:"kind"`
+		Reason    string     `json:"reason"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+)
+
+func (h *UserHandler) banUser(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	var req banUserReq
+	err := json.NewDecoder(io.LimitReader(r.Body, h.bytesLimit)).Decode(&req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return apperr.WithHTTPStatus(apperr.ErrEmptyBody, http.StatusBadRequest)
+		}
+		return apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+	defer r.Body.Close()
+
+	kind := entity.BanKind(req.Kind)
+	if kind == "" {
+		kind = entity.BanKindBan
+	}
+
+	err = h.UserUseCase.BanUser(r.Context(), adminID(r), userID, kind, req.Reason, req.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *UserHandler) unbanUser(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	err := h.UserUseCase.UnbanUser(r.Context(), adminID(r), userID)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func toUserResponse(user *entity.User) userResponse {
+	return userResponse{
+		UUID:        user.UUID,
+		Name:        user.Name,
+		Birthday:    user.BirthDay,
+		Sex:         user.Sex,
+		Phone:       user.Phone,
+		Role:        string(user.Role),
+		CreatedAt:   user.CreatedAt,
+		HiddenUntil: user.HiddenUntil,
+	}
+}
@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+type BlockUseCase interface {
+	Block(ctx context.Context, blockerID string, blockedID string) error
+	Unblock(ctx context.Context, blockerID string, blockedID string) error
+	GetBlocked(ctx context.Context, blockerID string) ([]*entity.BlockedUser, error)
+}
+
+type BlockHandler struct {
+	BlockUseCase
+	bytesLimit int64
+}
+
+func NewBlockHandler(bytesLimit int64, blockUseCase BlockUseCase) Handler {
+	return &BlockHandler{
+		BlockUseCase: blockUseCase,
+		bytesLimit:   bytesLimit,
+	}
+}
+
+func (h *BlockHandler) Register(r *httprouter.Router) {
+	r.GET("/v1/users/:id/blocks", errorHandler(h.getBlocked))
+	r.POST("/v1/users/:id/blocks", errorHandler(h.block))
+	r.DELETE("/v1/users/:id/blocks/:blocked_id", errorHandler(h.unblock))
+}
+
+type (
+	blockReq struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+)
+
+func (h *BlockHandler) block(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	var req blockReq
+	err := json.NewDecoder(io.LimitReader(r.Body, h.bytesLimit)).Decode(&req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return apperr.WithHTTPStatus(apperr.ErrEmptyBody, http.StatusBadRequest)
+		}
+		return apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+	defer r.Body.Close()
+
+	err = h.BlockUseCase.Block(r.Context(), userID, req.UserID.String())
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *BlockHandler) unblock(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+	blockedID := p.ByName("blocked_id")
+
+	err := h.BlockUseCase.Unblock(r.Context(), userID, blockedID)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+type (
+	blockedUserResponse struct {
+		UUID      uuid.UUID `json:"uuid"`
+		Name      string    `json:"name"`
+		BlockedAt time.Time `json:"blocked_at"`
+	}
+
+	getBlockedResponse struct {
+		Users []blockedUserResponse `json:"users"`
+	}
+)
+
+func (h *BlockHandler) getBlocked(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	blocked, err := h.BlockUseCase.GetBlocked(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	resp := getBlockedResponse{
+		Users: make([]blockedUserResponse, 0, len(blocked)),
+	}
+	for _, user := range blocked {
+		resp.Users = append(resp.Users, blockedUserResponse{
+			UUID:      user.UUID,
+			Name:      user.Name,
+			BlockedAt: user.BlockedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusInternalServerError)
+	}
+
+	return nil
+}
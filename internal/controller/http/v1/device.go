@@ -0,0 +1,79 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+type DeviceUseCase interface {
+	RegisterDevice(ctx context.Context, userID string, token string, platform entity.Platform) error
+	UnregisterDevice(ctx context.Context, userID string, token string) error
+}
+
+type DeviceHandler struct {
+	DeviceUseCase
+	bytesLimit int64
+}
+
+func NewDeviceHandler(bytesLimit int64, deviceUseCase DeviceUseCase) Handler {
+	return &DeviceHandler{
+		DeviceUseCase: deviceUseCase,
+		bytesLimit:    bytesLimit,
+	}
+}
+
+func (h *DeviceHandler) Register(r *httprouter.Router) {
+	r.POST("/v1/users/:id/devices", errorHandler(h.registerDevice))
+	r.DELETE("/v1/users/:id/devices/:token", errorHandler(h.unregisterDevice))
+}
+
+type (
+	registerDeviceReq struct {
+		Token    string `json:"token"`
+		Platform string `json:"platform"`
+	}
+)
+
+func (h *DeviceHandler) registerDevice(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	var req registerDeviceReq
+	err := json.NewDecoder(io.LimitReader(r.Body, h.bytesLimit)).Decode(&req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return apperr.WithHTTPStatus(apperr.ErrEmptyBody, http.StatusBadRequest)
+		}
+		return apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+	defer r.Body.Close()
+
+	err = h.DeviceUseCase.RegisterDevice(r.Context(), userID, req.Token, entity.Platform(req.Platform))
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *DeviceHandler) unregisterDevice(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+	token := p.ByName("token")
+
+	err := h.DeviceUseCase.UnregisterDevice(r.Context(), userID, token)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
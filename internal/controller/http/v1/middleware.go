@@ -1,10 +1,20 @@
 package v1
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/sirupsen/logrus"
 )
 
 type appHandler func(http.ResponseWriter, *http.Request, httprouter.Params) error
@@ -17,3 +27,162 @@ func errorHandler(f appHandler) httprouter.Handle {
 		}
 	}
 }
+
+// TODO: take the caller from the jwt once auth middleware is ready
+const viewerHeader = "X-User-ID"
+
+func viewerID(r *http.Request) string {
+	return r.Header.Get(viewerHeader)
+}
+
+const (
+	idempotencyHeader = "Idempotency-Key"
+	replayedHeader    = "Idempotent-Replayed"
+)
+
+type IdempotencyUseCase interface {
+	BeginRequest(ctx context.Context, userID string, key string, fingerprint string) (string, *entity.IdempotentResponse, error)
+	FinishRequest(ctx context.Context, scoped string, fingerprint string, resp *entity.IdempotentResponse) error
+	AbortRequest(ctx context.Context, scoped string) error
+}
+
+// idempotent replays the first response to POST requests that are retried
+// with the same Idempotency-Key header, a retry that arrives while the first
+// attempt is still running gets 409. A retry has to repeat the method, path
+// and body of the first attempt byte for byte, otherwise it gets 422. The body
+// is read before the handler runs, so it is capped at maxBodySize, the size of
+// the largest request the api accepts.
+//
+// Keys are scoped to the caller, so anonymous requests such as registration
+// would all share one namespace and replay each other's responses; they run
+// as usual, like requests without the header.
+func idempotent(u IdempotencyUseCase, maxMemory, maxBodySize int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		if r.Method != http.MethodPost || key == "" || viewerID(r) == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		fingerprint, cleanup, err := bufferBody(r, maxMemory)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer cleanup()
+
+		scoped, stored, err := u.BeginRequest(r.Context(), viewerID(r), key, fingerprint)
+		if err != nil {
+			http.Error(w, err.Error(), apperr.HTTPStatus(err))
+			return
+		}
+
+		if stored != nil {
+			if stored.ContentType != "" {
+				w.Header().Set("Content-Type", stored.ContentType)
+			}
+			w.Header().Set(replayedHeader, "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		// the response has to be stored even if the client is gone by now
+		ctx := context.WithoutCancel(r.Context())
+		rec := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			if rec.status == 0 {
+				err := u.AbortRequest(ctx, scoped)
+				if err != nil {
+					logrus.WithError(err).Error("failed to release idempotency key")
+				}
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			return
+		}
+
+		err = u.FinishRequest(ctx, scoped, fingerprint, &entity.IdempotentResponse{
+			Status:      rec.status,
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		})
+		if err != nil {
+			logrus.WithError(err).Error("failed to store idempotent response")
+		}
+	})
+}
+
+// bufferBody fingerprints the request by its method, path and body, and puts
+// the body back for the handler. Up to maxMemory bytes are kept in memory, the
+// rest goes to a temporary file the way multipart forms are parsed. cleanup
+// removes the file once the request is done.
+func bufferBody(r *http.Request, maxMemory int64) (fingerprint string, cleanup func(), err error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n", r.Method, r.URL.RequestURI())
+
+	var buf bytes.Buffer
+	n, err := io.CopyN(io.MultiWriter(&buf, hash), r.Body, maxMemory+1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", nil, fmt.Errorf("failed to read request body, err: %w", err)
+	}
+
+	if n <= maxMemory {
+		r.Body = io.NopCloser(&buf)
+		return hex.EncodeToString(hash.Sum(nil)), func() {}, nil
+	}
+
+	file, err := os.CreateTemp("", "idempotent-body-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create body file, err: %w", err)
+	}
+	cleanup = func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	_, err = io.Copy(file, &buf)
+	if err == nil {
+		_, err = io.Copy(io.MultiWriter(file, hash), r.Body)
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to buffer request body, err: %w", err)
+	}
+
+	r.Body = io.NopCloser(file)
+	return hex.EncodeToString(hash.Sum(nil)), cleanup, nil
+}
+
+// responseRecorder passes the response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
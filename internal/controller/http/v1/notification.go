@@ -0,0 +1,212 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+const (
+	defaultNotificationsLimit = 50
+	maxNotificationsLimit     = 100
+)
+
+type NotificationUseCase interface {
+	GetNotifications(ctx context.Context, userID string, unreadOnly bool, limit, offset uint64) ([]*entity.Notification, error)
+	MarkRead(ctx context.Context, userID string, notificationID string) error
+	MarkAllRead(ctx context.Context, userID string) error
+	GetPreferences(ctx context.Context, userID string) ([]*entity.NotificationPreference, error)
+	SetPreferences(ctx context.Context, userID string, preferences []*entity.NotificationPreference) error
+}
+
+type NotificationHandler struct {
+	NotificationUseCase
+	bytesLimit int64
+}
+
+func NewNotificationHandler(bytesLimit int64, notificationUseCase NotificationUseCase) Handler {
+	return &NotificationHandler{
+		NotificationUseCase: notificationUseCase,
+		bytesLimit:          bytesLimit,
+	}
+}
+
+func (h *NotificationHandler) Register(r *httprouter.Router) {
+	r.GET("/v1/users/:id/notifications", errorHandler(h.getNotifications))
+	r.PATCH("/v1/users/:id/notifications", errorHandler(h.markAllRead))
+	r.PATCH("/v1/users/:id/notifications/:notification_id", errorHandler(h.markRead))
+	r.GET("/v1/users/:id/notification-preferences", errorHandler(h.getPreferences))
+	r.PUT("/v1/users/:id/notification-preferences", errorHandler(h.setPreferences))
+}
+
+type (
+	notificationResponse struct {
+		ID        int64           `json:"id"`
+		Type      string          `json:"type"`
+		Payload   json.RawMessage `json:"payload"`
+		Read      bool            `json:"read"`
+		ReadAt    *time.Time      `json:"read_at,omitempty"`
+		CreatedAt time.Time       `json:"created_at"`
+	}
+
+	getNotificationsResponse struct {
+		Notifications []notificationResponse `json:"notifications"`
+	}
+)
+
+func (h *NotificationHandler) getNotifications(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+	query := r.URL.Query()
+
+	unreadOnly := query.Get("unread") == "true"
+
+	limit, err := parseUintQuery(query.Get("limit"), defaultNotificationsLimit)
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+	limit = min(limit, maxNotificationsLimit)
+
+	offset, err := parseUintQuery(query.Get("offset"), 0)
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+
+	notifications, err := h.NotificationUseCase.GetNotifications(r.Context(), userID, unreadOnly, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	resp := getNotificationsResponse{
+		Notifications: make([]notificationResponse, 0, len(notifications)),
+	}
+	for _, notification := range notifications {
+		resp.Notifications = append(resp.Notifications, notificationResponse{
+			ID:        notification.ID,
+			Type:      string(notification.Type),
+			Payload:   notification.Payload,
+			Read:      notification.ReadAt != nil,
+			ReadAt:    notification.ReadAt,
+			CreatedAt: notification.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (h *NotificationHandler) markRead(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+	notificationID := p.ByName("notification_id")
+
+	err := h.NotificationUseCase.MarkRead(r.Context(), userID, notificationID)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *NotificationHandler) markAllRead(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	err := h.NotificationUseCase.MarkAllRead(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+type (
+	notificationPreference struct {
+		Type  string `json:"type"`
+		Muted bool   `json:"muted"`
+	}
+
+	notificationPreferences struct {
+		Preferences []notificationPreference `json:"preferences"`
+	}
+)
+
+func (h *NotificationHandler) getPreferences(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	preferences, err := h.NotificationUseCase.GetPreferences(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	resp := notificationPreferences{
+		Preferences: make([]notificationPreference, 0, len(preferences)),
+	}
+	for _, preference := range preferences {
+		resp.Preferences = append(resp.Preferences, notificationPreference{
+			Type:  string(preference.Type),
+			Muted: preference.Muted,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (h *NotificationHandler) setPreferences(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	var req notificationPreferences
+	err := json.NewDecoder(io.LimitReader(r.Body, h.bytesLimit)).Decode(&req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return apperr.WithHTTPStatus(apperr.ErrEmptyBody, http.StatusBadRequest)
+		}
+		return apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+	defer r.Body.Close()
+
+	preferences := make([]*entity.NotificationPreference, 0, len(req.Preferences))
+	for _, preference := range req.Preferences {
+		preferences = append(preferences, &entity.NotificationPreference{
+			Type:  entity.NotificationType(preference.Type),
+			Muted: preference.Muted,
+		})
+	}
+
+	err = h.NotificationUseCase.SetPreferences(r.Context(), userID, preferences)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func parseUintQuery(value string, fallback uint64) (uint64, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	return strconv.ParseUint(value, 10, 64)
+}
@@ -0,0 +1,101 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+type ReportUseCase interface {
+	Report(ctx context.Context, reporterID string, report *entity.Report) (*entity.Report, error)
+}
+
+type ReportHandler struct {
+	ReportUseCase
+	bytesLimit int64
+}
+
+func NewReportHandler(bytesLimit int64, reportUseCase ReportUseCase) Handler {
+	return &ReportHandler{
+		ReportUseCase: reportUseCase,
+		bytesLimit:    bytesLimit,
+	}
+}
+
+func (h *ReportHandler) Register(r *httprouter.Router) {
+	r.POST("/v1/users/:id/reports", errorHandler(h.report))
+}
+
+type (
+	reportReq struct {
+		TargetType string `json:"target_type"`
+		TargetID   string `json:"target_id"`
+		Reason     string `json:"reason"`
+		Comment    string `json:"comment"`
+	}
+
+	reportResp struct {
+		ID         int64     `json:"id"`
+		TargetType string    `json:"target_type"`
+		TargetID   string    `json:"target_id"`
+		Reason     string    `json:"reason"`
+		Comment    string    `json:"comment"`
+		Status     string    `json:"status"`
+		CreatedAt  time.Time `json:"created_at"`
+	}
+)
+
+func (h *ReportHandler) report(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	// the reporter is the caller, the path only names whose reports these are
+	reporterID := viewerID(r)
+	if reporterID == "" {
+		return apperr.WithHTTPStatus(apperr.ErrUnauthenticated, http.StatusUnauthorized)
+	}
+	if reporterID != p.ByName("id") {
+		return apperr.WithHTTPStatus(apperr.ErrForbidden, http.StatusForbidden)
+	}
+
+	var req reportReq
+	err := json.NewDecoder(io.LimitReader(r.Body, h.bytesLimit)).Decode(&req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return apperr.WithHTTPStatus(apperr.ErrEmptyBody, http.StatusBadRequest)
+		}
+		return apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+	defer r.Body.Close()
+
+	report, err := h.ReportUseCase.Report(r.Context(), reporterID, &entity.Report{
+		TargetType: entity.ReportTargetType(req.TargetType),
+		TargetID:   req.TargetID,
+		Reason:     entity.ReportReason(req.Reason),
+		Comment:    req.Comment,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(reportResp{
+		ID:         report.ID,
+		TargetType: string(report.TargetType),
+		TargetID:   report.TargetID,
+		Reason:     string(report.Reason),
+		Comment:    report.Comment,
+		Status:     string(report.Status),
+		CreatedAt:  report.CreatedAt,
+	})
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusInternalServerError)
+	}
+
+	return nil
+}
@@ -11,7 +11,9 @@ type Handler interface {
 	Register(r *httprouter.Router)
 }
 
-func NewHandler(usecases *usecase.UseCases, bytesLimit, maxMemory int64) http.Handler {
+// NewHandler builds the api. maxBodySize bounds the requests that are read
+// before they reach their handler, it has to fit the largest photo upload.
+func NewHandler(usecases *usecase.UseCases, bytesLimit, maxMemory, maxBodySize int64) http.Handler {
 	r := httprouter.New()
 
 	authHandler := NewAuthHandler(bytesLimit, usecases.UserUseCase)
@@ -20,5 +22,17 @@ func NewHandler(usecases *usecase.UseCases, bytesLimit, maxMemory int64) http.Ha
 	userHandler := NewUserHandler(bytesLimit, maxMemory, usecases.UserUseCase, usecases.PhotoUseCase)
 	userHandler.Register(r)
 
-	return r
+	notificationHandler := NewNotificationHandler(bytesLimit, usecases.NotificationUseCase)
+	notificationHandler.Register(r)
+
+	deviceHandler := NewDeviceHandler(bytesLimit, usecases.PushUseCase)
+	deviceHandler.Register(r)
+
+	blockHandler := NewBlockHandler(bytesLimit, usecases.BlockUseCase)
+	blockHandler.Register(r)
+
+	reportHandler := NewReportHandler(bytesLimit, usecases.ReportUseCase)
+	reportHandler.Register(r)
+
+	return idempotent(usecases.IdempotencyUseCase, maxMemory, maxBodySize, r)
 }
@@ -3,6 +3,9 @@ package v1
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"time"
@@ -11,16 +14,24 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/kurochkinivan/Meet/internal/apperr"
 	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/kurochkinivan/Meet/pkg/imgproc"
 )
 
 type PhotoUseCase interface {
-	UploadPhotos(ctx context.Context, userID string, files []*multipart.FileHeader) error
+	UploadPhotos(ctx context.Context, userID string, files []*multipart.FileHeader) ([]*entity.PhotoUploadResult, error)
+	GetPhotoStatus(ctx context.Context, userID string, photoID string) (*entity.Photo, error)
+	CreateUploadURLs(ctx context.Context, userID string, files []*entity.PhotoUploadRequest) ([]*entity.PresignedUpload, error)
+	ConfirmUploads(ctx context.Context, userID string, uploadIDs []string) ([]*entity.PhotoUploadResult, error)
+	ReorderPhotos(ctx context.Context, userID string, photoIDs []int64) error
+	SetPrimaryPhoto(ctx context.Context, userID string, photoID int64) error
 	DeletePhoto(ctx context.Context, userID string, photoID string) error
-	GetPhotos(ctx context.Context, userID string) ([]*entity.Photo, error)
+	GetPhotos(ctx context.Context, viewerID string, userID string) ([]*entity.Photo, error)
 }
 
 type UserUseCase interface {
-	GetUserByID(ctx context.Context, userID string) (*entity.User, error)
+	GetUserByID(ctx context.Context, viewerID string, userID string) (*entity.User, error)
+	DeleteAccount(ctx context.Context, userID string, password string, oauthToken string) (time.Time, error)
+	CancelAccountDeletion(ctx context.Context, userID string, password string, oauthToken string) error
 }
 
 type UserHandler struct {
@@ -41,8 +52,15 @@ func NewUserHandler(bytesLimit int64, maxMemory int64, userUseCase UserUseCase,
 
 func (h *UserHandler) Register(r *httprouter.Router) {
 	r.GET("/v1/users/:id", errorHandler(h.getUser))
+	r.DELETE("/v1/users/:id", errorHandler(h.deleteAccount))
+	r.POST("/v1/users/:id/deletion/cancel", errorHandler(h.cancelAccountDeletion))
 	r.GET("/v1/users/:id/photos", errorHandler(h.getPhotos))
 	r.POST("/v1/users/:id/photos", errorHandler(h.uploadPhotos))
+	r.POST("/v1/users/:id/photos/uploads", errorHandler(h.createUploadURLs))
+	r.POST("/v1/users/:id/photos/uploads/confirm", errorHandler(h.confirmUploads))
+	r.GET("/v1/users/:id/photos/:photo_id/status", errorHandler(h.getPhotoStatus))
+	r.PUT("/v1/users/:id/photos/order", errorHandler(h.reorderPhotos))
+	r.PUT("/v1/users/:id/photos/primary", errorHandler(h.setPrimaryPhoto))
 	r.DELETE("/v1/users/:id/photo/:photo_id", errorHandler(h.deletePhoto))
 }
 
@@ -55,14 +73,251 @@ func (h *UserHandler) uploadPhotos(w http.ResponseWriter, r *http.Request, p htt
 	userID := p.ByName("id")
 	files := r.MultipartForm.File["photo"]
 
-	err = h.PhotoUseCase.UploadPhotos(r.Context(), userID, files)
+	results, err := h.PhotoUseCase.UploadPhotos(r.Context(), userID, files)
 	if err != nil {
 		return err
 	}
 
+	return writeUploadResults(w, userID, results)
+}
+
+type (
+	uploadPhotosResponse struct {
+		Files []uploadResultResponse `json:"files"`
+	}
+
+	uploadResultResponse struct {
+		Filename  string               `json:"filename,omitempty"`
+		UploadID  string               `json:"upload_id,omitempty"`
+		Status    string               `json:"status"`
+		PhotoID   int64                `json:"photo_id,omitempty"`
+		StatusURL string               `json:"status_url,omitempty"`
+		Error     *uploadErrorResponse `json:"error,omitempty"`
+	}
+
+	uploadErrorResponse struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	photoStatusResponse struct {
+		ID              int64  `json:"id"`
+		Processing      string `json:"processing"`
+		ProcessingError string `json:"processing_error,omitempty"`
+		Status          string `json:"status"`
+		RejectionReason string `json:"rejection_reason,omitempty"`
+	}
+)
+
+const (
+	uploadQueued = "queued"
+	uploadFailed = "failed"
+)
+
+// writeUploadResults lists every file of an upload, so that the client can
+// retry only the failed ones. The photos are not published yet, their URLs
+// show up on the status endpoint once processing is done.
+//
+// The response is 202 when every file was queued, 207 when only some were
+// and carries the status of the first failure when none was.
+func writeUploadResults(w http.ResponseWriter, userID string, results []*entity.PhotoUploadResult) error {
+	resp := &uploadPhotosResponse{
+		Files: make([]uploadResultResponse, 0, len(results)),
+	}
+
+	queued, failedStatus := 0, 0
+	for _, result := range results {
+		file := uploadResultResponse{
+			Filename: result.Filename,
+			UploadID: result.UploadID,
+		}
+
+		if result.Err != nil {
+			code, status := uploadErrorCode(result.Err)
+			if failedStatus == 0 {
+				failedStatus = status
+			}
+
+			file.Status = uploadFailed
+			file.Error = &uploadErrorResponse{
+				Code:    code,
+				Message: result.Err.Error(),
+			}
+		} else {
+			queued++
+
+			file.Status = uploadQueued
+			file.PhotoID = result.Photo.ID
+			file.StatusURL = fmt.Sprintf("/v1/users/%s/photos/%d/status", userID, result.Photo.ID)
+		}
+
+		resp.Files = append(resp.Files, file)
+	}
+
+	status := http.StatusAccepted
+	switch {
+	case queued == 0 && failedStatus != 0:
+		status = failedStatus
+	case queued < len(results):
+		status = http.StatusMultiStatus
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func uploadErrorCode(err error) (code string, status int) {
+	switch {
+	case errors.Is(err, imgproc.ErrFileTooLarge):
+		return "file_too_large", http.StatusRequestEntityTooLarge
+	case errors.Is(err, imgproc.ErrUnsupportedFormat):
+		return "unsupported_format", http.StatusUnsupportedMediaType
+	case errors.Is(err, imgproc.ErrDimensions):
+		return "invalid_dimensions", http.StatusUnprocessableEntity
+	case errors.Is(err, imgproc.ErrCorrupted):
+		return "corrupted_image", http.StatusUnprocessableEntity
+	case errors.Is(err, apperr.ErrPhotoLimitExceeded):
+		return "photo_limit_exceeded", http.StatusBadRequest
+	case errors.Is(err, apperr.ErrUploadNotFound):
+		return "upload_not_found", http.StatusNotFound
+	case errors.Is(err, apperr.ErrUploadConfirmed):
+		return "upload_already_confirmed", http.StatusConflict
+	case errors.Is(err, apperr.ErrUserNotFound):
+		return "user_not_found", http.StatusNotFound
+	default:
+		return "internal_error", apperr.HTTPStatus(err)
+	}
+}
+
+type (
+	createUploadURLsReq struct {
+		Files []struct {
+			ContentType string `json:"content_type"`
+			Size        int64  `json:"size"`
+		} `json:"files"`
+	}
+
+	createUploadURLsResponse struct {
+		Uploads []presignedUploadResponse `json:"uploads"`
+	}
+
+	presignedUploadResponse struct {
+		ID        string            `json:"id"`
+		URL       string            `json:"url"`
+		Method    string            `json:"method"`
+		Headers   map[string]string `json:"headers"`
+		ExpiresAt time.Time         `json:"expires_at"`
+	}
+
+	confirmUploadsReq struct {
+		UploadIDs []string `json:"upload_ids"`
+	}
+)
+
+func (h *UserHandler) createUploadURLs(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	var req createUploadURLsReq
+	err := json.NewDecoder(io.LimitReader(r.Body, h.bytesLimit)).Decode(&req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return apperr.WithHTTPStatus(apperr.ErrEmptyBody, http.StatusBadRequest)
+		}
+		return apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+	defer r.Body.Close()
+
+	files := make([]*entity.PhotoUploadRequest, 0, len(req.Files))
+	for _, file := range req.Files {
+		files = append(files, &entity.PhotoUploadRequest{
+			ContentType: file.ContentType,
+			Size:        file.Size,
+		})
+	}
+
+	uploads, err := h.PhotoUseCase.CreateUploadURLs(r.Context(), userID, files)
+	if err != nil {
+		return err
+	}
+
+	resp := &createUploadURLsResponse{
+		Uploads: make([]presignedUploadResponse, 0, len(uploads)),
+	}
+	for _, upload := range uploads {
+		resp.Uploads = append(resp.Uploads, presignedUploadResponse{
+			ID:        upload.ID,
+			URL:       upload.URL,
+			Method:    upload.Method,
+			Headers:   upload.Headers,
+			ExpiresAt: upload.ExpiresAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (h *UserHandler) confirmUploads(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	var req confirmUploadsReq
+	err := json.NewDecoder(io.LimitReader(r.Body, h.bytesLimit)).Decode(&req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return apperr.WithHTTPStatus(apperr.ErrEmptyBody, http.StatusBadRequest)
+		}
+		return apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+	defer r.Body.Close()
+
+	results, err := h.PhotoUseCase.ConfirmUploads(r.Context(), userID, req.UploadIDs)
+	if err != nil {
+		return err
+	}
+
+	return writeUploadResults(w, userID, results)
+}
+
+func (h *UserHandler) getPhotoStatus(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+	photoID := p.ByName("photo_id")
+
+	photo, err := h.PhotoUseCase.GetPhotoStatus(r.Context(), userID, photoID)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(toPhotoStatusResponse(photo))
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusInternalServerError)
+	}
+
 	return nil
 }
 
+func toPhotoStatusResponse(photo *entity.Photo) photoStatusResponse {
+	return photoStatusResponse{
+		ID:              photo.ID,
+		Processing:      string(photo.Processing),
+		ProcessingError: photo.ProcessingError,
+		Status:          string(photo.Status),
+		RejectionReason: photo.RejectionReason,
+	}
+}
+
 type (
 	getUserResponse struct {
 		UUID      uuid.UUID          `json:"uuid"`
@@ -76,15 +331,26 @@ type (
 	}
 
 	photoResponse struct {
-		ID  int64  `json:"id"`
-		URL string `json:"url"`
+		ID              int64                  `json:"id"`
+		URL             string                 `json:"url"`
+		BlurHash        string                 `json:"blurhash,omitempty"`
+		Variants        []photoVariantResponse `json:"variants"`
+		Processing      string                 `json:"processing,omitempty"`
+		Status          string                 `json:"status,omitempty"`
+		RejectionReason string                 `json:"rejection_reason,omitempty"`
+	}
+
+	photoVariantResponse struct {
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+		URL    string `json:"url"`
 	}
 )
 
 func (h *UserHandler) getUser(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
 	userID := p.ByName("id")
 
-	user, err := h.UserUseCase.GetUserByID(r.Context(), userID)
+	user, err := h.UserUseCase.GetUserByID(r.Context(), viewerID(r), userID)
 	if err != nil {
 		return err
 	}
@@ -102,8 +368,10 @@ func (h *UserHandler) getUser(w http.ResponseWriter, r *http.Request, p httprout
 
 	for _, photo := range user.Photos {
 		resp.Photos = append(resp.Photos, photoResponse{
-			ID:  photo.ID,
-			URL: photo.URL,
+			ID:       photo.ID,
+			URL:      photo.URL,
+			BlurHash: photo.BlurHash,
+			Variants: toPhotoVariantResponses(photo.Variants),
 		})
 	}
 
@@ -122,10 +390,77 @@ type (
 	}
 )
 
+type (
+	// accountOwnerReq proves the ownership of the account like a login does
+	accountOwnerReq struct {
+		Password   string `json:"password"`
+		OAuthToken string `json:"oauth_token"`
+	}
+
+	deleteAccountResponse struct {
+		DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+	}
+)
+
+func (h *UserHandler) decodeAccountOwner(r *http.Request) (*accountOwnerReq, error) {
+	var req accountOwnerReq
+	err := json.NewDecoder(io.LimitReader(r.Body, h.bytesLimit)).Decode(&req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, apperr.WithHTTPStatus(apperr.ErrEmptyBody, http.StatusBadRequest)
+		}
+		return nil, apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+	defer r.Body.Close()
+
+	return &req, nil
+}
+
+func (h *UserHandler) deleteAccount(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	req, err := h.decodeAccountOwner(r)
+	if err != nil {
+		return err
+	}
+
+	scheduledAt, err := h.UserUseCase.DeleteAccount(r.Context(), userID, req.Password, req.OAuthToken)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(w).Encode(&deleteAccountResponse{DeletionScheduledAt: scheduledAt})
+	if err != nil {
+		return apperr.WithHTTPStatus(err, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (h *UserHandler) cancelAccountDeletion(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	req, err := h.decodeAccountOwner(r)
+	if err != nil {
+		return err
+	}
+
+	err = h.UserUseCase.CancelAccountDeletion(r.Context(), userID, req.Password, req.OAuthToken)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
 func (h *UserHandler) getPhotos(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
 	userID := p.ByName("id")
 
-	photos, err := h.PhotoUseCase.GetPhotos(r.Context(), userID)
+	photos, err := h.PhotoUseCase.GetPhotos(r.Context(), viewerID(r), userID)
 	if err != nil {
 		return err
 	}
@@ -135,8 +470,13 @@ func (h *UserHandler) getPhotos(w http.ResponseWriter, r *http.Request, p httpro
 	}
 	for _, photo := range photos {
 		resp.Photos = append(resp.Photos, photoResponse{
-			ID:  photo.ID,
-			URL: photo.URL,
+			ID:              photo.ID,
+			URL:             photo.URL,
+			BlurHash:        photo.BlurHash,
+			Variants:        toPhotoVariantResponses(photo.Variants),
+			Processing:      string(photo.Processing),
+			Status:          string(photo.Status),
+			RejectionReason: photo.RejectionReason,
 		})
 	}
 
@@ -149,6 +489,62 @@ func (h *UserHandler) getPhotos(w http.ResponseWriter, r *http.Request, p httpro
 	return nil
 }
 
+type (
+	reorderPhotosReq struct {
+		PhotoIDs []int64 `json:"photo_ids"`
+	}
+
+	setPrimaryPhotoReq struct {
+		PhotoID int64 `json:"photo_id"`
+	}
+)
+
+func (h *UserHandler) reorderPhotos(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	var req reorderPhotosReq
+	err := json.NewDecoder(io.LimitReader(r.Body, h.bytesLimit)).Decode(&req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return apperr.WithHTTPStatus(apperr.ErrEmptyBody, http.StatusBadRequest)
+		}
+		return apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+	defer r.Body.Close()
+
+	err = h.PhotoUseCase.ReorderPhotos(r.Context(), userID, req.PhotoIDs)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *UserHandler) setPrimaryPhoto(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := p.ByName("id")
+
+	var req setPrimaryPhotoReq
+	err := json.NewDecoder(io.LimitReader(r.Body, h.bytesLimit)).Decode(&req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return apperr.WithHTTPStatus(apperr.ErrEmptyBody, http.StatusBadRequest)
+		}
+		return apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+	defer r.Body.Close()
+
+	err = h.PhotoUseCase.SetPrimaryPhoto(r.Context(), userID, req.PhotoID)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
 func (h *UserHandler) deletePhoto(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
 	userID := p.ByName("id")
 	photoID := p.ByName("photo_id")
@@ -162,3 +558,15 @@ func (h *UserHandler) deletePhoto(w http.ResponseWriter, r *http.Request, p http
 
 	return nil
 }
+
+func toPhotoVariantResponses(variants []*entity.PhotoVariant) []photoVariantResponse {
+	resp := make([]photoVariantResponse, 0, len(variants))
+	for _, variant := range variants {
+		resp = append(resp, photoVariantResponse{
+			Width:  variant.Width,
+			Height: variant.Height,
+			URL:    variant.URL,
+		})
+	}
+	return resp
+}
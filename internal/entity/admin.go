@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
+type BanKind string
+
+const (
+	// BanKindBan locks the user out of the app.
+	BanKindBan BanKind = "ban"
+	// BanKindShadowban keeps the user signed in but invisible to everyone else.
+	BanKindShadowban BanKind = "shadowban"
+)
+
+func (k BanKind) Valid() bool {
+	switch k {
+	case BanKindBan, BanKindShadowban:
+		return true
+	}
+	return false
+}
+
+type Ban struct {
+	UserID    uuid.UUID
+	Kind      BanKind
+	Reason    string
+	BannedBy  uuid.NullUUID
+	ExpiresAt *time.Time
+	CreatedAt time.Time
+}
+
+type AuditAction string
+
+const (
+	AuditBanUser            AuditAction = "ban_user"
+	AuditUnbanUser          AuditAction = "unban_user"
+	AuditDeletePhoto        AuditAction = "delete_photo"
+	AuditApprovePhoto       AuditAction = "approve_photo"
+	AuditRejectPhoto        AuditAction = "reject_photo"
+	AuditUpdateReportStatus AuditAction = "update_report_status"
+)
+
+type AuditEntry struct {
+	ID         int64
+	ActorID    uuid.UUID
+	Action     AuditAction
+	TargetType string
+	TargetID   string
+	Details    json.RawMessage
+	CreatedAt  time.Time
+}
+
+type UserProfile struct {
+	User    *User
+	Photos  []*Photo
+	Reports []*Report
+	Ban     *Ban
+}
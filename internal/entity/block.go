@@ -0,0 +1,13 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BlockedUser struct {
+	UUID      uuid.UUID
+	Name      string
+	BlockedAt time.Time
+}
@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Platform string
+
+const (
+	PlatformFCM  Platform = "fcm"
+	PlatformAPNs Platform = "apns"
+)
+
+func (p Platform) Valid() bool {
+	return p == PlatformFCM || p == PlatformAPNs
+}
+
+type DeviceToken struct {
+	ID        int64
+	UserID    uuid.UUID
+	Token     string
+	Platform  Platform
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type PushMessage struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
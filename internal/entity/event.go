@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventPhotoCreated  EventType = "photo.created"
+	EventPhotoDeleted  EventType = "photo.deleted"
+	EventPhotoApproved EventType = "photo.approved"
+	EventPhotoRejected EventType = "photo.rejected"
+	// EventPhotoDuplicate is emitted when a processed photo turns out to be a
+	// near duplicate of a photo of another account.
+	EventPhotoDuplicate EventType = "photo.duplicate"
+)
+
+type Event struct {
+	ID          int64
+	Type        EventType
+	AggregateID string
+	Payload     json.RawMessage
+	Attempts    int
+	DeliveredTo []string
+	CreatedAt   time.Time
+}
+
+type PhotoEventPayload struct {
+	PhotoID     int64    `json:"photo_id"`
+	UserID      string   `json:"user_id"`
+	ObjectKey   string   `json:"object_key"`
+	VariantKeys []string `json:"variant_keys,omitempty"`
+	Reason      string   `json:"reason,omitempty"`
+	DuplicateOf int64    `json:"duplicate_of,omitempty"`
+}
@@ -0,0 +1,16 @@
+package entity
+
+// IdempotentResponse is the first response to a request with an idempotency
+// key, it is replayed to the retries of that request.
+type IdempotentResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// IdempotentRequest is kept for an idempotency key: the fingerprint of the
+// request that claimed the key and, once it is answered, its response.
+type IdempotentRequest struct {
+	Fingerprint string              `json:"fingerprint"`
+	Response    *IdempotentResponse `json:"response,omitempty"`
+}
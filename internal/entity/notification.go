@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NotificationType string
+
+const (
+	NotificationNewMatch      NotificationType = "new_match"
+	NotificationNewMessage    NotificationType = "new_message"
+	NotificationProfileLiked  NotificationType = "profile_liked"
+	NotificationPhotoRejected NotificationType = "photo_rejected"
+)
+
+func (t NotificationType) Valid() bool {
+	switch t {
+	case NotificationNewMatch, NotificationNewMessage, NotificationProfileLiked, NotificationPhotoRejected:
+		return true
+	}
+	return false
+}
+
+type Notification struct {
+	ID        int64
+	UserID    uuid.UUID
+	Type      NotificationType
+	Payload   json.RawMessage
+	ReadAt    *time.Time
+	CreatedAt time.Time
+}
+
+type NotificationPreference struct {
+	Type  NotificationType
+	Muted bool
+}
@@ -6,10 +6,109 @@ import (
 	"github.com/google/uuid"
 )
 
+type PhotoStatus string
+
+const (
+	PhotoStatusPending  PhotoStatus = "pending"
+	PhotoStatusApproved PhotoStatus = "approved"
+	PhotoStatusRejected PhotoStatus = "rejected"
+)
+
+func (s PhotoStatus) Valid() bool {
+	switch s {
+	case PhotoStatusPending, PhotoStatusApproved, PhotoStatusRejected:
+		return true
+	}
+	return false
+}
+
+// ProcessingState tracks an upload through the processing pipeline: it is
+// staged as queued, picked up by a worker and ends up ready or failed.
+type ProcessingState string
+
+const (
+	ProcessingQueued     ProcessingState = "queued"
+	ProcessingInProgress ProcessingState = "processing"
+	ProcessingReady      ProcessingState = "ready"
+	ProcessingFailed     ProcessingState = "failed"
+)
+
 type Photo struct {
 	ID        int64
 	UserID    uuid.UUID
 	URL       string
 	ObjectKey string
+	BlurHash  string
 	CreatedAt time.Time
+	// Position orders the photos of a user, the first one is the primary photo
+	Position int
+
+	Processing         ProcessingState
+	ProcessingError    string
+	ProcessingAttempts int
+	Status             PhotoStatus
+	RejectionReason    string
+	HiddenUntil        *time.Time
+	DuplicateOf        *int64
+	Variants           []*PhotoVariant
+}
+
+// PhotoCluster groups near duplicate photos of different accounts. ID is the
+// earliest photo of the cluster.
+type PhotoCluster struct {
+	ID     int64
+	Photos []*Photo
+}
+
+// PhotoVariant is a downscaled copy of the photo stored next to the original.
+type PhotoVariant struct {
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	ObjectKey string `json:"object_key"`
+	URL       string `json:"url"`
+}
+
+func (p *Photo) Hidden() bool {
+	return p.HiddenUntil != nil && p.HiddenUntil.After(time.Now())
+}
+
+// Visible reports whether the photo may be shown to users other than its owner.
+func (p *Photo) Visible() bool {
+	return p.Processing == ProcessingReady && p.Status == PhotoStatusApproved && !p.Hidden()
+}
+
+// ObjectKeys returns the keys of the original and all of its variants.
+func (p *Photo) ObjectKeys() []string {
+	keys := make([]string, 0, len(p.Variants)+1)
+	keys = append(keys, p.ObjectKey)
+	for _, variant := range p.Variants {
+		keys = append(keys, variant.ObjectKey)
+	}
+	return keys
+}
+
+// PhotoUploadResult is the outcome of a single file of an upload, the file is
+// named by Filename for multipart uploads and by UploadID for direct ones.
+// Either Photo or Err is set.
+type PhotoUploadResult struct {
+	Filename string
+	UploadID string
+	Photo    *Photo
+	Err      error
+}
+
+// PhotoUploadRequest describes a file the client is going to upload directly.
+type PhotoUploadRequest struct {
+	ContentType string
+	Size        int64
+}
+
+// PresignedUpload lets the client put a photo straight into the staging area.
+// The request must carry Headers as they are, they are part of the signature.
+type PresignedUpload struct {
+	ID        string
+	URL       string
+	Method    string
+	Headers   map[string]string
+	ExpiresAt time.Time
 }
@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// StoredObject is an object found in the blob storage.
+type StoredObject struct {
+	Key          string
+	LastModified time.Time
+}
+
+// ReconcileReport lists where the photos table and the blob storage disagree.
+type ReconcileReport struct {
+	// OrphanObjects are stored objects no photo refers to
+	OrphanObjects []string
+	// DanglingPhotos are processed photos whose original is gone
+	DanglingPhotos []int64
+	// DanglingVariants are keys of recorded variants whose object is gone
+	DanglingVariants []string
+	DryRun           bool
+}
@@ -0,0 +1,87 @@
+package entity
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReportTargetType string
+
+const (
+	ReportTargetUser    ReportTargetType = "user"
+	ReportTargetPhoto   ReportTargetType = "photo"
+	ReportTargetMessage ReportTargetType = "message"
+)
+
+func (t ReportTargetType) Valid() bool {
+	switch t {
+	case ReportTargetUser, ReportTargetPhoto, ReportTargetMessage:
+		return true
+	}
+	return false
+}
+
+type ReportReason string
+
+const (
+	ReportReasonSpam                 ReportReason = "spam"
+	ReportReasonFakeProfile          ReportReason = "fake_profile"
+	ReportReasonInappropriateContent ReportReason = "inappropriate_content"
+	ReportReasonHarassment           ReportReason = "harassment"
+	ReportReasonUnderage             ReportReason = "underage"
+	ReportReasonOther                ReportReason = "other"
+)
+
+func (r ReportReason) Valid() bool {
+	switch r {
+	case ReportReasonSpam, ReportReasonFakeProfile, ReportReasonInappropriateContent,
+		ReportReasonHarassment, ReportReasonUnderage, ReportReasonOther:
+		return true
+	}
+	return false
+}
+
+// HidingReportReasons are the reasons severe enough to temporarily hide the
+// target until a moderator reviews it.
+var HidingReportReasons = []ReportReason{ReportReasonUnderage, ReportReasonInappropriateContent}
+
+func (r ReportReason) HidesTarget() bool {
+	return slices.Contains(HidingReportReasons, r)
+}
+
+type ReportStatus string
+
+const (
+	ReportStatusOpen      ReportStatus = "open"
+	ReportStatusReviewing ReportStatus = "reviewing"
+	ReportStatusActioned  ReportStatus = "actioned"
+	ReportStatusDismissed ReportStatus = "dismissed"
+)
+
+func (s ReportStatus) Valid() bool {
+	switch s {
+	case ReportStatusOpen, ReportStatusReviewing, ReportStatusActioned, ReportStatusDismissed:
+		return true
+	}
+	return false
+}
+
+// Unresolved reports whether moderators have not decided on the report yet.
+func (s ReportStatus) Unresolved() bool {
+	return s == ReportStatusOpen || s == ReportStatusReviewing
+}
+
+type Report struct {
+	ID          int64
+	ReporterID  uuid.NullUUID
+	TargetType  ReportTargetType
+	TargetID    string
+	Reason      ReportReason
+	Comment     string
+	Status      ReportStatus
+	TargetCount int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
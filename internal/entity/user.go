@@ -13,9 +13,20 @@ type User struct {
 	Sex       string
 	Phone     string
 	Password  string
+	Role      Role
 	Location  Coordiantes
 	CreatedAt time.Time
 	Photos    []*Photo
+
+	HiddenUntil *time.Time
+	// DeletionScheduledAt is set while the account waits for its erasure
+	DeletionScheduledAt *time.Time
+}
+
+// Hidden reports whether others can't see the user, accounts that are going
+// to be erased are hidden for the whole grace period.
+func (u *User) Hidden() bool {
+	return u.DeletionScheduledAt != nil || u.HiddenUntil != nil && u.HiddenUntil.After(time.Now())
 }
 
 type Coordiantes struct {
@@ -0,0 +1,66 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+// FileSender is a stand-in for FCM/APNs that appends every push as a JSON line
+// to a local file, so it can be inspected during local development.
+type FileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{
+		path: path,
+	}
+}
+
+type filePush struct {
+	UserID   string            `json:"user_id"`
+	Platform string            `json:"platform"`
+	Token    string            `json:"token"`
+	Title    string            `json:"title"`
+	Body     string            `json:"body"`
+	Data     map[string]string `json:"data,omitempty"`
+	SentAt   time.Time         `json:"sent_at"`
+}
+
+func (s *FileSender) Send(ctx context.Context, token *entity.DeviceToken, message *entity.PushMessage) error {
+	line, err := json.Marshal(filePush{
+		UserID:   token.UserID.String(),
+		Platform: string(token.Platform),
+		Token:    token.Token,
+		Title:    message.Title,
+		Body:     message.Body,
+		Data:     message.Data,
+		SentAt:   time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal push, err: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open push file %q, err: %w", s.path, err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write push to file %q, err: %w", s.path, err)
+	}
+
+	return nil
+}
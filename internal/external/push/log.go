@@ -0,0 +1,28 @@
+package push
+
+import (
+	"context"
+
+	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/sirupsen/logrus"
+)
+
+// LogSender is a stand-in for FCM/APNs that only logs outgoing pushes.
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(ctx context.Context, token *entity.DeviceToken, message *entity.PushMessage) error {
+	logrus.WithFields(logrus.Fields{
+		"user_id":  token.UserID,
+		"platform": token.Platform,
+		"token":    token.Token,
+		"title":    message.Title,
+		"body":     message.Body,
+		"data":     message.Data,
+	}).Info("push notification sent")
+
+	return nil
+}
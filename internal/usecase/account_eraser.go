@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/sirupsen/logrus"
+)
+
+// AccountEraser finishes the deletion of accounts whose grace period is over.
+// The rows go through the postgres cascades, the objects of the user are
+// deleted here.
+type AccountEraser struct {
+	AccountStorage
+	ObjectEraser
+	UserCache
+	interval  time.Duration
+	batchSize uint64
+}
+
+func NewAccountEraser(storage AccountStorage, objects ObjectEraser, cache UserCache, interval time.Duration, batchSize uint64) *AccountEraser {
+	return &AccountEraser{
+		AccountStorage: storage,
+		ObjectEraser:   objects,
+		UserCache:      cache,
+		interval:       interval,
+		batchSize:      batchSize,
+	}
+}
+
+type AccountStorage interface {
+	GetDueDeletions(ctx context.Context, limit uint64) ([]string, error)
+	DeleteUser(ctx context.Context, userID string) error
+}
+
+type ObjectEraser interface {
+	ObjectLister
+	DeletePhoto(ctx context.Context, objectKey string) error
+}
+
+func (e *AccountEraser) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := e.EraseDueAccounts(ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				logrus.WithError(err).Error("failed to erase accounts")
+			}
+		}
+	}
+}
+
+// EraseDueAccounts erases one batch of accounts. An account that fails is
+// left as it is and tried again next time.
+func (e *AccountEraser) EraseDueAccounts(ctx context.Context) error {
+	userIDs, err := e.AccountStorage.GetDueDeletions(ctx, e.batchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get due deletions, err: %w", err)
+	}
+
+	var errs []error
+	for _, userID := range userIDs {
+		err = e.erase(ctx, userID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to erase account %s, err: %w", userID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// erase deletes the objects before the user, so that a failure leaves the
+// user in place to be retried instead of objects nothing points at.
+func (e *AccountEraser) erase(ctx context.Context, userID string) error {
+	for _, prefix := range []string{photosPrefix + userID + "/", stagingPrefix + userID + "/"} {
+		objects, err := e.ObjectEraser.ListObjects(ctx, prefix)
+		if err != nil {
+			return fmt.Errorf("failed to list objects, err: %w", err)
+		}
+
+		for _, object := range objects {
+			err = e.ObjectEraser.DeletePhoto(ctx, object.Key)
+			if err != nil {
+				return fmt.Errorf("failed to delete object %s, err: %w", object.Key, err)
+			}
+		}
+	}
+
+	err := e.AccountStorage.DeleteUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNoRows) {
+			logrus.WithField("user_id", userID).Warn("account is gone or no longer due for erasure")
+			return nil
+		}
+		return fmt.Errorf("failed to delete user, err: %w", err)
+	}
+
+	err = e.UserCache.Delete(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to evict user from cache, err: %w", err)
+	}
+
+	logrus.WithField("user_id", userID).Info("account erased")
+
+	return nil
+}
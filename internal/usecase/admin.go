@@ -0,0 +1,390 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+type AdminUseCase struct {
+	AdminUserStorage
+	AdminPhotoStorage
+	AdminReportStorage
+	BanStorage
+	AuditStorage
+	EventStorage
+	ProfileCache
+	TxManager
+	photos  *PhotoUseCase
+	reports *ReportUseCase
+}
+
+func NewAdminUseCase(userStorage AdminUserStorage, photoStorage AdminPhotoStorage, reportStorage AdminReportStorage, banStorage BanStorage, auditStorage AuditStorage, events EventStorage, cache ProfileCache, txManager TxManager, photos *PhotoUseCase, reports *ReportUseCase) *AdminUseCase {
+	return &AdminUseCase{
+		AdminUserStorage:   userStorage,
+		AdminPhotoStorage:  photoStorage,
+		AdminReportStorage: reportStorage,
+		BanStorage:         banStorage,
+		AuditStorage:       auditStorage,
+		EventStorage:       events,
+		ProfileCache:       cache,
+		TxManager:          txManager,
+		photos:             photos,
+		reports:            reports,
+	}
+}
+
+type AdminUserStorage interface {
+	GetByID(ctx context.Context, userID string) (*entity.User, error)
+	GetRole(ctx context.Context, userID string) (entity.Role, error)
+	SearchUsers(ctx context.Context, query string, limit, offset uint64) ([]*entity.User, error)
+}
+
+type AdminPhotoStorage interface {
+	GetPhotos(ctx context.Context, userID string) ([]*entity.Photo, error)
+	GetPhoto(ctx context.Context, photoID string) (*entity.Photo, error)
+	GetPhotosByStatus(ctx context.Context, status entity.PhotoStatus, limit, offset uint64) ([]*entity.Photo, error)
+	SetPhotoStatus(ctx context.Context, photoID string, status entity.PhotoStatus, reason string, reviewerID string) (*entity.Photo, error)
+	GetDuplicateClusters(ctx context.Context, limit, offset uint64) ([]*entity.PhotoCluster, error)
+}
+
+type AdminReportStorage interface {
+	GetReportsByTarget(ctx context.Context, targetType entity.ReportTargetType, targetID string) ([]*entity.Report, error)
+}
+
+type BanStorage interface {
+	BanChecker
+	Ban(ctx context.Context, ban *entity.Ban) error
+	Unban(ctx context.Context, userID string) error
+}
+
+type AuditStorage interface {
+	CreateAuditEntry(ctx context.Context, entry *entity.AuditEntry) error
+}
+
+func (u *AdminUseCase) IsAdmin(ctx context.Context, userID string) (bool, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return false, nil
+	}
+
+	role, err := u.AdminUserStorage.GetRole(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperr.ErrUserNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get user role, err: %w", err)
+	}
+
+	return role == entity.RoleAdmin, nil
+}
+
+func (u *AdminUseCase) SearchUsers(ctx context.Context, query string, limit, offset uint64) ([]*entity.User, error) {
+	users, err := u.AdminUserStorage.SearchUsers(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users, err: %w", err)
+	}
+
+	return users, nil
+}
+
+// GetUserProfile returns everything moderators need to judge a user: the
+// profile with hidden photos included, reports against them and an active ban.
+func (u *AdminUseCase) GetUserProfile(ctx context.Context, userID string) (*entity.UserProfile, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, apperr.WithHTTPStatus(apperr.ErrUserNotFound, http.StatusNotFound)
+	}
+
+	user, err := u.AdminUserStorage.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user, err: %w", err)
+	}
+
+	if user.UUID == uuid.Nil {
+		return nil, apperr.WithHTTPStatus(apperr.ErrUserNotFound, http.StatusNotFound)
+	}
+
+	photos, err := u.AdminPhotoStorage.GetPhotos(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get photos, err: %w", err)
+	}
+	user.Photos = photos
+
+	err = u.photos.urls.SignPhotos(ctx, photos...)
+	if err != nil {
+		return nil, err
+	}
+
+	reports, err := u.AdminReportStorage.GetReportsByTarget(ctx, entity.ReportTargetUser, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reports, err: %w", err)
+	}
+
+	ban, err := getBan(ctx, u.BanStorage, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.UserProfile{
+		User:    user,
+		Photos:  photos,
+		Reports: reports,
+		Ban:     ban,
+	}, nil
+}
+
+// BanUser bans or shadowbans the user. A nil expiresAt makes the ban
+// permanent.
+func (u *AdminUseCase) BanUser(ctx context.Context, adminID string, userID string, kind entity.BanKind, reason string, expiresAt *time.Time) error {
+	admin, err := uuid.Parse(adminID)
+	if err != nil {
+		return apperr.WithHTTPStatus(apperr.ErrUnauthenticated, http.StatusUnauthorized)
+	}
+
+	if !kind.Valid() {
+		return apperr.WithHTTPStatus(fmt.Errorf("%w: unknown kind %q", apperr.ErrInvalidBan, kind), http.StatusBadRequest)
+	}
+
+	if reason == "" {
+		return apperr.WithHTTPStatus(fmt.Errorf("%w: empty reason", apperr.ErrInvalidBan), http.StatusBadRequest)
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return apperr.WithHTTPStatus(fmt.Errorf("%w: expiration is in the past", apperr.ErrInvalidBan), http.StatusBadRequest)
+	}
+
+	target, err := uuid.Parse(userID)
+	if err != nil {
+		return apperr.WithHTTPStatus(apperr.ErrUserNotFound, http.StatusNotFound)
+	}
+
+	err = u.TxManager.Do(ctx, func(ctx context.Context) error {
+		err := u.BanStorage.Ban(ctx, &entity.Ban{
+			UserID:    target,
+			Kind:      kind,
+			Reason:    reason,
+			BannedBy:  uuid.NullUUID{UUID: admin, Valid: true},
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		return u.audit(ctx, admin, entity.AuditBanUser, "user", userID, map[string]any{
+			"kind":       kind,
+			"reason":     reason,
+			"expires_at": expiresAt,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ban user, err: %w", err)
+	}
+
+	return u.ProfileCache.Delete(ctx, userID)
+}
+
+func (u *AdminUseCase) UnbanUser(ctx context.Context, adminID string, userID string) error {
+	admin, err := uuid.Parse(adminID)
+	if err != nil {
+		return apperr.WithHTTPStatus(apperr.ErrUnauthenticated, http.StatusUnauthorized)
+	}
+
+	if _, err := uuid.Parse(userID); err != nil {
+		return apperr.WithHTTPStatus(apperr.ErrUserNotFound, http.StatusNotFound)
+	}
+
+	err = u.TxManager.Do(ctx, func(ctx context.Context) error {
+		err := u.BanStorage.Unban(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		return u.audit(ctx, admin, entity.AuditUnbanUser, "user", userID, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unban user, err: %w", err)
+	}
+
+	return u.ProfileCache.Delete(ctx, userID)
+}
+
+func (u *AdminUseCase) DeletePhoto(ctx context.Context, adminID string, photoID string) error {
+	admin, err := uuid.Parse(adminID)
+	if err != nil {
+		return apperr.WithHTTPStatus(apperr.ErrUnauthenticated, http.StatusUnauthorized)
+	}
+
+	if _, err := strconv.ParseInt(photoID, 10, 64); err != nil {
+		return apperr.WithHTTPStatus(apperr.ErrNoRows, http.StatusNotFound)
+	}
+
+	err = u.TxManager.Do(ctx, func(ctx context.Context) error {
+		photo, err := u.AdminPhotoStorage.GetPhoto(ctx, photoID)
+		if err != nil {
+			if errors.Is(err, apperr.ErrNoRows) {
+				return apperr.WithHTTPStatus(err, http.StatusNotFound)
+			}
+			return err
+		}
+
+		err = u.photos.DeletePhoto(ctx, photo.UserID.String(), photoID)
+		if err != nil {
+			return err
+		}
+
+		return u.audit(ctx, admin, entity.AuditDeletePhoto, "photo", photoID, map[string]string{"user_id": photo.UserID.String()})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete photo, err: %w", err)
+	}
+
+	return nil
+}
+
+func (u *AdminUseCase) GetPhotoQueue(ctx context.Context, status entity.PhotoStatus, limit, offset uint64) ([]*entity.Photo, error) {
+	if !status.Valid() {
+		return nil, apperr.WithHTTPStatus(fmt.Errorf("%w: unknown status %q", apperr.ErrInvalidPhotoReview, status), http.StatusBadRequest)
+	}
+
+	photos, err := u.AdminPhotoStorage.GetPhotosByStatus(ctx, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get photo queue, err: %w", err)
+	}
+
+	err = u.photos.urls.SignPhotos(ctx, photos...)
+	if err != nil {
+		return nil, err
+	}
+
+	return photos, nil
+}
+
+// GetDuplicateClusters lists photos used by more than one account, which is
+// usually a sign of catfishing.
+func (u *AdminUseCase) GetDuplicateClusters(ctx context.Context, limit, offset uint64) ([]*entity.PhotoCluster, error) {
+	clusters, err := u.AdminPhotoStorage.GetDuplicateClusters(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get duplicate clusters, err: %w", err)
+	}
+
+	for _, cluster := range clusters {
+		err = u.photos.urls.SignPhotos(ctx, cluster.Photos...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return clusters, nil
+}
+
+// ReviewPhoto approves or rejects the photo. Publishing the object and
+// notifying the owner happen through the outbox once the decision is stored.
+func (u *AdminUseCase) ReviewPhoto(ctx context.Context, adminID string, photoID string, status entity.PhotoStatus, reason string) error {
+	admin, err := uuid.Parse(adminID)
+	if err != nil {
+		return apperr.WithHTTPStatus(apperr.ErrUnauthenticated, http.StatusUnauthorized)
+	}
+
+	var action entity.AuditAction
+	var eventType entity.EventType
+	switch status {
+	case entity.PhotoStatusApproved:
+		action, eventType, reason = entity.AuditApprovePhoto, entity.EventPhotoApproved, ""
+	case entity.PhotoStatusRejected:
+		if reason == "" {
+			return apperr.WithHTTPStatus(fmt.Errorf("%w: rejection needs a reason", apperr.ErrInvalidPhotoReview), http.StatusBadRequest)
+		}
+		action, eventType = entity.AuditRejectPhoto, entity.EventPhotoRejected
+	default:
+		return apperr.WithHTTPStatus(fmt.Errorf("%w: unknown status %q", apperr.ErrInvalidPhotoReview, status), http.StatusBadRequest)
+	}
+
+	if _, err := strconv.ParseInt(photoID, 10, 64); err != nil {
+		return apperr.WithHTTPStatus(apperr.ErrNoRows, http.StatusNotFound)
+	}
+
+	var photo *entity.Photo
+	err = u.TxManager.Do(ctx, func(ctx context.Context) error {
+		photo, err = u.AdminPhotoStorage.SetPhotoStatus(ctx, photoID, status, reason, adminID)
+		if err != nil {
+			if errors.Is(err, apperr.ErrNoRows) {
+				return apperr.WithHTTPStatus(err, http.StatusNotFound)
+			}
+			return err
+		}
+
+		err = u.EventStorage.CreateEvent(ctx, eventType, photo.UserID.String(), entity.PhotoEventPayload{
+			PhotoID:     photo.ID,
+			UserID:      photo.UserID.String(),
+			ObjectKey:   photo.ObjectKey,
+			VariantKeys: variantKeys(photo),
+			Reason:      reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		return u.audit(ctx, admin, action, "photo", photoID, map[string]string{"reason": reason})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to review photo, err: %w", err)
+	}
+
+	return u.ProfileCache.Delete(ctx, photo.UserID.String())
+}
+
+func (u *AdminUseCase) GetReportQueue(ctx context.Context, status entity.ReportStatus, limit, offset uint64) ([]*entity.Report, error) {
+	return u.reports.GetReportQueue(ctx, status, limit, offset)
+}
+
+func (u *AdminUseCase) UpdateReportStatus(ctx context.Context, adminID string, reportID string, status entity.ReportStatus) error {
+	admin, err := uuid.Parse(adminID)
+	if err != nil {
+		return apperr.WithHTTPStatus(apperr.ErrUnauthenticated, http.StatusUnauthorized)
+	}
+
+	var unhiddenUserID string
+	err = u.TxManager.Do(ctx, func(ctx context.Context) error {
+		unhiddenUserID, err = u.reports.UpdateReportStatus(ctx, reportID, status)
+		if err != nil {
+			return err
+		}
+
+		return u.audit(ctx, admin, entity.AuditUpdateReportStatus, "report", reportID, map[string]string{"status": string(status)})
+	})
+	if err != nil {
+		return err
+	}
+
+	if unhiddenUserID != "" {
+		return u.ProfileCache.Delete(ctx, unhiddenUserID)
+	}
+
+	return nil
+}
+
+func (u *AdminUseCase) audit(ctx context.Context, actorID uuid.UUID, action entity.AuditAction, targetType string, targetID string, details any) error {
+	entry := &entity.AuditEntry{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+
+	if details != nil {
+		data, err := json.Marshal(details)
+		if err != nil {
+			return apperr.WithHTTPStatus(fmt.Errorf("failed to marshal audit details, err: %w", err), http.StatusInternalServerError)
+		}
+		entry.Details = data
+	}
+
+	return u.AuditStorage.CreateAuditEntry(ctx, entry)
+}
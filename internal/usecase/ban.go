@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+type BanChecker interface {
+	GetBan(ctx context.Context, userID string) (*entity.Ban, error)
+}
+
+func getBan(ctx context.Context, checker BanChecker, userID string) (*entity.Ban, error) {
+	ban, err := checker.GetBan(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get ban, err: %w", err)
+	}
+
+	return ban, nil
+}
+
+// ensureNotBanned refuses to sign in a banned user. Shadowbanned users are let
+// through so they don't notice the restriction.
+func ensureNotBanned(ctx context.Context, checker BanChecker, userID string) error {
+	ban, err := getBan(ctx, checker, userID)
+	if err != nil {
+		return err
+	}
+
+	if ban == nil || ban.Kind != entity.BanKindBan {
+		return nil
+	}
+
+	if ban.ExpiresAt != nil {
+		return apperr.WithHTTPStatus(fmt.Errorf("%w until %s: %s", apperr.ErrUserBanned, ban.ExpiresAt.Format(time.RFC3339), ban.Reason), http.StatusForbidden)
+	}
+
+	return apperr.WithHTTPStatus(fmt.Errorf("%w: %s", apperr.ErrUserBanned, ban.Reason), http.StatusForbidden)
+}
+
+// ensureVisible hides banned and shadowbanned users from everyone but
+// themselves.
+func ensureVisible(ctx context.Context, checker BanChecker, viewerID string, userID string) error {
+	if viewerID == userID {
+		return nil
+	}
+
+	ban, err := getBan(ctx, checker, userID)
+	if err != nil {
+		return err
+	}
+
+	if ban != nil {
+		return apperr.WithHTTPStatus(apperr.ErrUserNotFound, http.StatusNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+type BlockUseCase struct {
+	BlockStorage
+}
+
+func NewBlockUseCase(storage BlockStorage) *BlockUseCase {
+	return &BlockUseCase{
+		BlockStorage: storage,
+	}
+}
+
+type BlockStorage interface {
+	BlockChecker
+	Block(ctx context.Context, blockerID string, blockedID string) error
+	Unblock(ctx context.Context, blockerID string, blockedID string) error
+	GetBlocked(ctx context.Context, blockerID string) ([]*entity.BlockedUser, error)
+}
+
+type BlockChecker interface {
+	IsBlocked(ctx context.Context, userID string, otherID string) (bool, error)
+}
+
+func (u *BlockUseCase) Block(ctx context.Context, blockerID string, blockedID string) error {
+	if blockerID == blockedID {
+		return apperr.WithHTTPStatus(apperr.ErrSelfBlock, http.StatusBadRequest)
+	}
+
+	err := u.BlockStorage.Block(ctx, blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("failed to block user, err: %w", err)
+	}
+
+	return nil
+}
+
+func (u *BlockUseCase) Unblock(ctx context.Context, blockerID string, blockedID string) error {
+	err := u.BlockStorage.Unblock(ctx, blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("failed to unblock user, err: %w", err)
+	}
+
+	return nil
+}
+
+func (u *BlockUseCase) GetBlocked(ctx context.Context, blockerID string) ([]*entity.BlockedUser, error) {
+	blocked, err := u.BlockStorage.GetBlocked(ctx, blockerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocked users, err: %w", err)
+	}
+
+	return blocked, nil
+}
+
+// ensureNotBlocked hides the user from the viewer when either of them blocked
+// the other. Anonymous viewers are turned away, otherwise leaving out the
+// header would get around the block.
+//
+// The viewer is whoever the X-User-ID header names, so until requests are
+// authenticated this only keeps well-behaved clients apart.
+func ensureNotBlocked(ctx context.Context, checker BlockChecker, viewerID string, userID string) error {
+	if viewerID == "" {
+		return apperr.WithHTTPStatus(apperr.ErrUnauthenticated, http.StatusUnauthorized)
+	}
+
+	if uuid.Validate(viewerID) != nil {
+		return apperr.WithHTTPStatus(errors.New("viewer id must be a uuid"), http.StatusBadRequest)
+	}
+
+	if uuid.Validate(userID) != nil {
+		return apperr.WithHTTPStatus(apperr.ErrUserNotFound, http.StatusNotFound)
+	}
+
+	if viewerID == userID {
+		return nil
+	}
+
+	blocked, err := checker.IsBlocked(ctx, viewerID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to check block, err: %w", err)
+	}
+
+	if blocked {
+		return apperr.WithHTTPStatus(apperr.ErrUserNotFound, http.StatusNotFound)
+	}
+
+	return nil
+}
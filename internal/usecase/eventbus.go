@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+type EventHandler func(ctx context.Context, event *entity.Event) error
+
+// EventBus is the in-process event sink: it fans events out to the handlers
+// subscribed to their type. A failing handler makes Publish fail, so the relay
+// redelivers the event and handlers must be idempotent.
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[entity.EventType][]EventHandler
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		handlers: make(map[entity.EventType][]EventHandler),
+	}
+}
+
+func (b *EventBus) Subscribe(eventType entity.EventType, handler EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+func (b *EventBus) Name() string {
+	return "eventbus"
+}
+
+func (b *EventBus) Publish(ctx context.Context, event *entity.Event) error {
+	b.mu.RLock()
+	handlers := b.handlers[event.Type]
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		err := handler(ctx, event)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("handler for event %q failed, err: %w", event.Type, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
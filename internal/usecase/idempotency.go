@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+// maxIdempotencyKeyLength is generous for the uuids clients are expected to send.
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyUseCase makes retries of a request with the same idempotency key
+// get the response of the first attempt instead of running again.
+type IdempotencyUseCase struct {
+	IdempotencyStorage
+	window  time.Duration
+	lockTTL time.Duration
+}
+
+func NewIdempotencyUseCase(storage IdempotencyStorage, window time.Duration, lockTTL time.Duration) *IdempotencyUseCase {
+	return &IdempotencyUseCase{
+		IdempotencyStorage: storage,
+		window:             window,
+		lockTTL:            lockTTL,
+	}
+}
+
+type IdempotencyStorage interface {
+	Claim(ctx context.Context, key string, fingerprint string, ttl time.Duration) (bool, *entity.IdempotentRequest, error)
+	SaveResponse(ctx context.Context, key string, req *entity.IdempotentRequest, ttl time.Duration) error
+	Release(ctx context.Context, key string) error
+}
+
+// BeginRequest claims the key for the request, the key is scoped to the caller
+// so that clients can't collide. fingerprint identifies the request: reusing
+// the key for a request with another fingerprint is rejected. The stored
+// response is returned when the request has been answered already, nil means
+// the request has to run and be finished with FinishRequest.
+func (u *IdempotencyUseCase) BeginRequest(ctx context.Context, userID string, key string, fingerprint string) (string, *entity.IdempotentResponse, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return "", nil, apperr.WithHTTPStatus(apperr.ErrInvalidIdempotencyKey, http.StatusBadRequest)
+	}
+
+	sum := sha256.Sum256([]byte(userID + "\n" + key))
+	scoped := hex.EncodeToString(sum[:])
+
+	claimed, stored, err := u.IdempotencyStorage.Claim(ctx, scoped, fingerprint, u.lockTTL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if claimed {
+		return scoped, nil, nil
+	}
+
+	if stored != nil && stored.Fingerprint != fingerprint {
+		return "", nil, apperr.WithHTTPStatus(apperr.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity)
+	}
+
+	if stored == nil || stored.Response == nil {
+		return "", nil, apperr.WithHTTPStatus(apperr.ErrRequestInFlight, http.StatusConflict)
+	}
+
+	return scoped, stored.Response, nil
+}
+
+// FinishRequest stores the response for the rest of the window. Server errors
+// aren't stored, the key is released so that a retry runs the request again.
+func (u *IdempotencyUseCase) FinishRequest(ctx context.Context, scoped string, fingerprint string, resp *entity.IdempotentResponse) error {
+	if resp.Status >= http.StatusInternalServerError {
+		return u.AbortRequest(ctx, scoped)
+	}
+
+	err := u.IdempotencyStorage.SaveResponse(ctx, scoped, &entity.IdempotentRequest{
+		Fingerprint: fingerprint,
+		Response:    resp,
+	}, u.window)
+	if err != nil {
+		return fmt.Errorf("failed to finish idempotent request, err: %w", err)
+	}
+
+	return nil
+}
+
+// AbortRequest releases a key whose request didn't produce a response.
+func (u *IdempotencyUseCase) AbortRequest(ctx context.Context, scoped string) error {
+	err := u.IdempotencyStorage.Release(ctx, scoped)
+	if err != nil {
+		return fmt.Errorf("failed to abort idempotent request, err: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+type NotificationUseCase struct {
+	NotificationStorage
+	Pusher
+}
+
+func NewNotificationUseCase(storage NotificationStorage, pusher Pusher) *NotificationUseCase {
+	return &NotificationUseCase{
+		NotificationStorage: storage,
+		Pusher:              pusher,
+	}
+}
+
+type NotificationStorage interface {
+	CreateNotification(ctx context.Context, userID string, notificationType entity.NotificationType, payload json.RawMessage) (*entity.Notification, error)
+	GetNotifications(ctx context.Context, userID string, unreadOnly bool, limit, offset uint64) ([]*entity.Notification, error)
+	MarkRead(ctx context.Context, userID string, notificationID string) error
+	MarkAllRead(ctx context.Context, userID string) error
+	IsMuted(ctx context.Context, userID string, notificationType entity.NotificationType) (bool, error)
+	GetPreferences(ctx context.Context, userID string) ([]*entity.NotificationPreference, error)
+	SetPreference(ctx context.Context, userID string, preference *entity.NotificationPreference) error
+}
+
+type Pusher interface {
+	Push(userID string, message *entity.PushMessage)
+}
+
+var pushTitles = map[entity.NotificationType]string{
+	entity.NotificationNewMatch:      "It's a match!",
+	entity.NotificationNewMessage:    "New message",
+	entity.NotificationProfileLiked:  "Someone liked your profile",
+	entity.NotificationPhotoRejected: "Your photo was rejected",
+}
+
+// Notify is the entry point for domain events that should reach the user's
+// notification center. Muted notification types are silently skipped.
+func (u *NotificationUseCase) Notify(ctx context.Context, userID string, notificationType entity.NotificationType, payload any) error {
+	muted, err := u.NotificationStorage.IsMuted(ctx, userID, notificationType)
+	if err != nil {
+		return fmt.Errorf("failed to check notification preferences, err: %w", err)
+	}
+
+	if muted {
+		return nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload, err: %w", err)
+	}
+
+	notification, err := u.NotificationStorage.CreateNotification(ctx, userID, notificationType, data)
+	if err != nil {
+		return fmt.Errorf("failed to create notification, err: %w", err)
+	}
+
+	u.Pusher.Push(userID, &entity.PushMessage{
+		Title: pushTitles[notificationType],
+		Data: map[string]string{
+			"notification_id": strconv.FormatInt(notification.ID, 10),
+			"type":            string(notificationType),
+		},
+	})
+
+	return nil
+}
+
+func (u *NotificationUseCase) HandlePhotoRejected(ctx context.Context, event *entity.Event) error {
+	var payload entity.PhotoEventPayload
+	err := json.Unmarshal(event.Payload, &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal %q payload, err: %w", event.Type, err)
+	}
+
+	return u.Notify(ctx, payload.UserID, entity.NotificationPhotoRejected, map[string]any{
+		"photo_id": payload.PhotoID,
+		"reason":   payload.Reason,
+	})
+}
+
+func (u *NotificationUseCase) GetNotifications(ctx context.Context, userID string, unreadOnly bool, limit, offset uint64) ([]*entity.Notification, error) {
+	notifications, err := u.NotificationStorage.GetNotifications(ctx, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications, err: %w", err)
+	}
+
+	return notifications, nil
+}
+
+func (u *NotificationUseCase) MarkRead(ctx context.Context, userID string, notificationID string) error {
+	err := u.NotificationStorage.MarkRead(ctx, userID, notificationID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification as read, err: %w", err)
+	}
+
+	return nil
+}
+
+func (u *NotificationUseCase) MarkAllRead(ctx context.Context, userID string) error {
+	err := u.NotificationStorage.MarkAllRead(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark all notifications as read, err: %w", err)
+	}
+
+	return nil
+}
+
+func (u *NotificationUseCase) GetPreferences(ctx context.Context, userID string) ([]*entity.NotificationPreference, error) {
+	preferences, err := u.NotificationStorage.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences, err: %w", err)
+	}
+
+	return preferences, nil
+}
+
+func (u *NotificationUseCase) SetPreferences(ctx context.Context, userID string, preferences []*entity.NotificationPreference) error {
+	for _, preference := range preferences {
+		if !preference.Type.Valid() {
+			return apperr.WithHTTPStatus(fmt.Errorf("%w: %q", apperr.ErrUnknownNotificationType, preference.Type), http.StatusBadRequest)
+		}
+	}
+
+	for _, preference := range preferences {
+		err := u.NotificationStorage.SetPreference(ctx, userID, preference)
+		if err != nil {
+			return fmt.Errorf("failed to set notification preference, err: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/sirupsen/logrus"
+)
+
+type OutboxRelay struct {
+	OutboxStorage
+	sinks        []EventSink
+	pollInterval time.Duration
+	batchSize    uint64
+	retry        OutboxRetry
+}
+
+// OutboxRetry controls how failed events are retried. The delay starts at Base
+// and doubles on every failure up to Max; after MaxAttempts failures the event
+// is dead-lettered. ClaimTimeout is how long a claimed event is hidden from
+// other relays, it has to cover publishing a whole batch.
+type OutboxRetry struct {
+	ClaimTimeout time.Duration
+	MaxAttempts  int
+	Base         time.Duration
+	Max          time.Duration
+}
+
+func NewOutboxRelay(storage OutboxStorage, pollInterval time.Duration, batchSize uint64, retry OutboxRetry, sinks ...EventSink) *OutboxRelay {
+	return &OutboxRelay{
+		OutboxStorage: storage,
+		sinks:         sinks,
+		pollInterval:  pollInterval,
+		batchSize:     batchSize,
+		retry:         retry,
+	}
+}
+
+type OutboxStorage interface {
+	ClaimEvents(ctx context.Context, limit uint64, leaseFor time.Duration) ([]*entity.Event, error)
+	MarkPublished(ctx context.Context, eventID int64) error
+	MarkFailed(ctx context.Context, eventID int64, reason string, deliveredTo []string, retryAt time.Time, dead bool) error
+}
+
+// EventSink is a destination of outbox events. Name identifies the sink in
+// the outbox, so it must be stable across restarts.
+type EventSink interface {
+	Name() string
+	Publish(ctx context.Context, event *entity.Event) error
+}
+
+// Run polls the outbox and publishes events to every sink. An event is marked
+// as published only after all sinks accepted it; a failed event is retried
+// with backoff only for the sinks that rejected it, which gives at-least-once
+// delivery without holding back the events behind it.
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := r.relay(ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				logrus.WithError(err).Error("failed to relay outbox events")
+			}
+		}
+	}
+}
+
+func (r *OutboxRelay) relay(ctx context.Context) error {
+	events, err := r.OutboxStorage.ClaimEvents(ctx, r.batchSize, r.retry.ClaimTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to claim outbox events, err: %w", err)
+	}
+
+	for _, event := range events {
+		deliveredTo, err := r.publish(ctx, event)
+		if err != nil {
+			attempts := event.Attempts + 1
+			dead := attempts >= r.retry.MaxAttempts
+
+			entry := logrus.WithError(err).WithFields(logrus.Fields{
+				"event_id":   event.ID,
+				"event_type": event.Type,
+				"attempts":   attempts,
+			})
+			if dead {
+				entry.Error("giving up on event")
+			} else {
+				entry.Warn("failed to publish event")
+			}
+
+			err = r.OutboxStorage.MarkFailed(ctx, event.ID, err.Error(), deliveredTo, time.Now().Add(r.backoff(attempts)), dead)
+			if err != nil {
+				return fmt.Errorf("failed to mark event %d as failed, err: %w", event.ID, err)
+			}
+			continue
+		}
+
+		err = r.OutboxStorage.MarkPublished(ctx, event.ID)
+		if err != nil {
+			return fmt.Errorf("failed to mark event %d as published, err: %w", event.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// publish sends the event to the sinks that have not accepted it yet and
+// returns the names of all sinks that have.
+func (r *OutboxRelay) publish(ctx context.Context, event *entity.Event) ([]string, error) {
+	deliveredTo := slices.Clone(event.DeliveredTo)
+
+	var errs []error
+	for _, sink := range r.sinks {
+		if slices.Contains(event.DeliveredTo, sink.Name()) {
+			continue
+		}
+
+		err := sink.Publish(ctx, event)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+			continue
+		}
+		deliveredTo = append(deliveredTo, sink.Name())
+	}
+
+	return deliveredTo, errors.Join(errs...)
+}
+
+// backoff returns the delay before the next attempt of an event that failed
+// attempts times.
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	delay := r.retry.Base
+	for i := 1; i < attempts && delay < r.retry.Max; i++ {
+		delay *= 2
+	}
+
+	return min(delay, r.retry.Max)
+}
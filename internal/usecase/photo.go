@@ -1,15 +1,21 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"slices"
+	"strconv"
 
+	"github.com/google/uuid"
 	"github.com/kurochkinivan/Meet/internal/apperr"
 	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/kurochkinivan/Meet/pkg/imgproc"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -17,27 +23,54 @@ type PhotoUseCase struct {
 	PhotoStorage
 	PhotoCloud
 	PhotoCache
-	photoLimit int
+	EventStorage
+	BlockChecker
+	BanChecker
+	DeletionChecker
+	TxManager
+	urls          *PhotoURLSigner
+	photoLimit    int
+	imageLimits   imgproc.Limits
+	variantWidths []int
+	staged        chan struct{}
 }
 
-func NewPhotoUseCase(storage PhotoStorage, cloud PhotoCloud, cache PhotoCache, photoLimit int) *PhotoUseCase {
+func NewPhotoUseCase(storage PhotoStorage, cloud PhotoCloud, cache PhotoCache, events EventStorage, blockChecker BlockChecker, banChecker BanChecker, deletionChecker DeletionChecker, txManager TxManager, urls *PhotoURLSigner, photoLimit int, imageLimits imgproc.Limits, variantWidths []int) *PhotoUseCase {
 	return &PhotoUseCase{
-		PhotoStorage: storage,
-		PhotoCloud:   cloud,
-		PhotoCache:   cache,
-		photoLimit:   photoLimit,
+		PhotoStorage:    storage,
+		PhotoCloud:      cloud,
+		PhotoCache:      cache,
+		EventStorage:    events,
+		BlockChecker:    blockChecker,
+		BanChecker:      banChecker,
+		DeletionChecker: deletionChecker,
+		TxManager:       txManager,
+		urls:            urls,
+		photoLimit:      photoLimit,
+		imageLimits:     imageLimits,
+		variantWidths:   variantWidths,
+		staged:          make(chan struct{}, 1),
 	}
 }
 
 type PhotoStorage interface {
-	CreatePhoto(ctx context.Context, userID string, url string, objectKey string) error
+	CreateStagedPhoto(ctx context.Context, userID string, stagingKey string) (*entity.Photo, error)
+	CreatePhotoVariant(ctx context.Context, photoID int64, variant *entity.PhotoVariant) error
 	GetPhotos(ctx context.Context, userID string) ([]*entity.Photo, error)
 	GetPhoto(ctx context.Context, photoID string) (*entity.Photo, error)
 	DeletePhoto(ctx context.Context, userID string, photoID string) error
+	LockPhotoIDs(ctx context.Context, userID string) ([]int64, error)
+	LockPhotoCount(ctx context.Context, userID string) (int, error)
+	SetPhotoPositions(ctx context.Context, userID string, photoIDs []int64) error
 }
 
 type PhotoCloud interface {
-	UploadPhoto(ctx context.Context, userID string, file io.Reader) (url string, objectKey string, err error)
+	UploadStaging(ctx context.Context, userID string, file io.Reader) (objectKey string, err error)
+	PresignStaging(ctx context.Context, userID string, contentType string, size int64) (*entity.PresignedUpload, error)
+	HeadStaging(ctx context.Context, userID string, uploadID string) (objectKey string, size int64, err error)
+	DownloadPhoto(ctx context.Context, objectKey string) ([]byte, error)
+	UploadPhoto(ctx context.Context, userID string, file io.Reader, format imgproc.Format) (url string, objectKey string, err error)
+	UploadVariant(ctx context.Context, originalKey string, width int, file io.Reader, format imgproc.Format) (url string, objectKey string, err error)
 	DeletePhoto(ctx context.Context, objectKey string) error
 }
 
@@ -45,83 +78,460 @@ type PhotoCache interface {
 	Delete(ctx context.Context, userID string) error
 }
 
-func (u *PhotoUseCase) UploadPhotos(ctx context.Context, userID string, files []*multipart.FileHeader) error {
-	photos, err := u.PhotoStorage.GetPhotos(ctx, userID)
+type EventStorage interface {
+	CreateEvent(ctx context.Context, eventType entity.EventType, aggregateID string, payload any) error
+}
+
+// UploadPhotos puts the files into the staging area and queues them for
+// processing. The returned photos are not visible to anyone but the owner
+// until the processor has validated and published them.
+//
+// Every file succeeds or fails on its own, the results follow the order of
+// the files. The photo limit is enforced per file as the photos are created,
+// so concurrent uploads can't overshoot it together.
+func (u *PhotoUseCase) UploadPhotos(ctx context.Context, userID string, files []*multipart.FileHeader) ([]*entity.PhotoUploadResult, error) {
+	// nothing can succeed once the limit is reached, there is no point in storing the files
+	err := u.checkPhotoLimit(ctx, userID, 1)
 	if err != nil {
-		return fmt.Errorf("failed to get all photos, err: %w", err)
+		return nil, err
 	}
 
-	if len(photos)+len(files) > u.photoLimit {
-		return apperr.WithHTTPStatus(errors.New("photo limit exceeded"), http.StatusBadRequest)
-	}
+	results := make([]*entity.PhotoUploadResult, len(files))
 
-	erg, ctx := errgroup.WithContext(ctx)
+	// a failed file must not cancel the others, hence no errgroup context
+	var erg errgroup.Group
 	erg.SetLimit(10)
 
-	for _, file := range files {
+	for i, file := range files {
 		erg.Go(func() error {
-			f, err := file.Open()
-			if err != nil {
-				return apperr.WithHTTPStatus(fmt.Errorf("failed to open file, err: %w", err), http.StatusInternalServerError)
+			photo, err := u.stagePhoto(ctx, userID, file)
+			results[i] = &entity.PhotoUploadResult{
+				Filename: file.Filename,
+				Photo:    photo,
+				Err:      err,
 			}
-			defer f.Close()
+			return nil
+		})
+	}
 
-			url, objectKey, err := u.PhotoCloud.UploadPhoto(ctx, userID, f)
-			if err != nil {
-				return fmt.Errorf("failed to upload photo, err: %w", err)
-			}
+	erg.Wait()
+	u.wakeProcessor()
+
+	return results, nil
+}
+
+// CreateUploadURLs signs direct uploads to the staging area, so that photo
+// bytes don't pass through the server. The uploads have to be confirmed with
+// ConfirmUploads before they become photos.
+func (u *PhotoUseCase) CreateUploadURLs(ctx context.Context, userID string, files []*entity.PhotoUploadRequest) ([]*entity.PresignedUpload, error) {
+	err := u.checkPhotoLimit(ctx, userID, len(files))
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := make([]*entity.PresignedUpload, 0, len(files))
+	for _, file := range files {
+		_, err = imgproc.ParseContentType(file.ContentType)
+		if err != nil {
+			return nil, apperr.WithHTTPStatus(fmt.Errorf("content type %q: %w", file.ContentType, err), http.StatusUnsupportedMediaType)
+		}
+
+		if file.Size <= 0 {
+			return nil, apperr.WithHTTPStatus(errors.New("file size must be positive"), http.StatusBadRequest)
+		}
+
+		if file.Size > u.imageLimits.MaxFileSize {
+			return nil, apperr.WithHTTPStatus(imgproc.ErrFileTooLarge, http.StatusRequestEntityTooLarge)
+		}
+
+		upload, err := u.PhotoCloud.PresignStaging(ctx, userID, file.ContentType, file.Size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to presign upload, err: %w", err)
+		}
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, nil
+}
+
+// ConfirmUploads checks that the direct uploads have arrived and queues them
+// for processing like regular uploads. Like UploadPhotos it reports every
+// upload on its own.
+func (u *PhotoUseCase) ConfirmUploads(ctx context.Context, userID string, uploadIDs []string) ([]*entity.PhotoUploadResult, error) {
+	err := u.checkPhotoLimit(ctx, userID, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*entity.PhotoUploadResult, 0, len(uploadIDs))
+	defer u.wakeProcessor()
+
+	for _, uploadID := range uploadIDs {
+		photo, err := u.confirmUpload(ctx, userID, uploadID)
+		results = append(results, &entity.PhotoUploadResult{
+			UploadID: uploadID,
+			Photo:    photo,
+			Err:      err,
+		})
+	}
+
+	return results, nil
+}
 
-			err = u.PhotoStorage.CreatePhoto(ctx, userID, url, objectKey)
-			if err != nil {
-				errDelete := u.PhotoCloud.DeletePhoto(ctx, objectKey)
-				if errDelete != nil {
-					return fmt.Errorf("failed to create photo: %w; rollback failed: %v", err, errDelete)
-				}
-				return fmt.Errorf("failed to create photo, rollback cloud upload, err: %w", err)
+func (u *PhotoUseCase) confirmUpload(ctx context.Context, userID string, uploadID string) (*entity.Photo, error) {
+	if uuid.Validate(uploadID) != nil {
+		return nil, apperr.ErrUploadNotFound
+	}
+
+	stagingKey, size, err := u.PhotoCloud.HeadStaging(ctx, userID, uploadID)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNoRows) {
+			return nil, apperr.ErrUploadNotFound
+		}
+		return nil, fmt.Errorf("failed to check upload, err: %w", err)
+	}
+
+	if size > u.imageLimits.MaxFileSize {
+		err = u.PhotoCloud.DeletePhoto(ctx, stagingKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete oversized upload, err: %w", err)
+		}
+		return nil, imgproc.ErrFileTooLarge
+	}
+
+	photo, err := u.createStagedPhoto(ctx, userID, stagingKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create photo, err: %w", err)
+	}
+
+	return photo, nil
+}
+
+// checkPhotoLimit rejects a request early when the photos can't fit. It is
+// only a hint, createStagedPhoto is what enforces the limit.
+func (u *PhotoUseCase) checkPhotoLimit(ctx context.Context, userID string, added int) error {
+	photos, err := u.PhotoStorage.GetPhotos(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get all photos, err: %w", err)
+	}
+
+	count := 0
+	for _, photo := range photos {
+		if photo.Processing != entity.ProcessingFailed {
+			count++
+		}
+	}
+
+	if count+added > u.photoLimit {
+		return apperr.WithHTTPStatus(apperr.ErrPhotoLimitExceeded, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// createStagedPhoto records a staged upload while holding the lock on the
+// user, so the photo count can't change between the check and the insert.
+// The errors are bare sentinels, the handlers turn them into per-file codes.
+func (u *PhotoUseCase) createStagedPhoto(ctx context.Context, userID string, stagingKey string) (*entity.Photo, error) {
+	var photo *entity.Photo
+	err := u.TxManager.Do(ctx, func(ctx context.Context) error {
+		count, err := u.PhotoStorage.LockPhotoCount(ctx, userID)
+		if err != nil {
+			if errors.Is(err, apperr.ErrNoRows) {
+				return apperr.ErrUserNotFound
 			}
+			return err
+		}
+
+		if count >= u.photoLimit {
+			return apperr.ErrPhotoLimitExceeded
+		}
+
+		photo, err = u.PhotoStorage.CreateStagedPhoto(ctx, userID, stagingKey)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	return photo, nil
+}
+
+// stagePhoto uploads the raw file to the staging area and queues it. The
+// format and header dimensions are checked up front, so the caller learns
+// about files that are not images at all; the worker still does the full
+// validation. The staging object is removed again if the photo can't be
+// recorded.
+func (u *PhotoUseCase) stagePhoto(ctx context.Context, userID string, file *multipart.FileHeader) (*entity.Photo, error) {
+	if file.Size > u.imageLimits.MaxFileSize {
+		return nil, imgproc.ErrFileTooLarge
+	}
+
+	data, err := u.readPhoto(file)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = imgproc.Validate(data, u.imageLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	stagingKey, err := u.PhotoCloud.UploadStaging(ctx, userID, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload photo to staging, err: %w", err)
+	}
+
+	photo, err := u.createStagedPhoto(ctx, userID, stagingKey)
+	if err != nil {
+		errDelete := u.PhotoCloud.DeletePhoto(ctx, stagingKey)
+		if errDelete != nil {
+			return nil, fmt.Errorf("failed to create photo: %w; rollback failed: %v", err, errDelete)
+		}
+		return nil, fmt.Errorf("failed to create photo, rollback staging upload, err: %w", err)
+	}
+
+	return photo, nil
+}
+
+func (u *PhotoUseCase) wakeProcessor() {
+	select {
+	case u.staged <- struct{}{}:
+	default:
+	}
+}
+
+// uploadVariants adds the uploaded variants to the photo as it goes, so a
+// failure leaves the photo listing everything that has to be cleaned up.
+func (u *PhotoUseCase) uploadVariants(ctx context.Context, photo *entity.Photo, upload *photoUpload) error {
+	variants, err := imgproc.Variants(upload.data, upload.info.Format, u.variantWidths)
+	if err != nil {
+		// HEIC can't be decoded here, such photos are served as originals only
+		if errors.Is(err, imgproc.ErrUnsupportedFormat) {
 			return nil
+		}
+		return fmt.Errorf("failed to make photo variants, err: %w", err)
+	}
+
+	for _, variant := range variants {
+		url, objectKey, err := u.PhotoCloud.UploadVariant(ctx, photo.ObjectKey, variant.Width, bytes.NewReader(variant.Data), variant.Format)
+		if err != nil {
+			return fmt.Errorf("failed to upload photo variant, err: %w", err)
+		}
+
+		photo.Variants = append(photo.Variants, &entity.PhotoVariant{
+			Width:     variant.Width,
+			Height:    variant.Height,
+			ObjectKey: objectKey,
+			URL:       url,
 		})
 	}
 
-	err = u.PhotoCache.Delete(ctx, userID)
+	return nil
+}
+
+func variantKeys(photo *entity.Photo) []string {
+	keys := make([]string, 0, len(photo.Variants))
+	for _, variant := range photo.Variants {
+		keys = append(keys, variant.ObjectKey)
+	}
+	return keys
+}
+
+type photoUpload struct {
+	data []byte
+	info *imgproc.Info
+}
+
+func (u *PhotoUseCase) readPhoto(file *multipart.FileHeader) ([]byte, error) {
+	f, err := file.Open()
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to open file, err: %w", err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, u.imageLimits.MaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file, err: %w", err)
+	}
+
+	if int64(len(data)) > u.imageLimits.MaxFileSize {
+		return nil, imgproc.ErrFileTooLarge
+	}
+
+	return data, nil
+}
+
+// preparePhoto validates a staged upload and strips its metadata.
+func (u *PhotoUseCase) preparePhoto(data []byte) (*photoUpload, error) {
+	info, err := imgproc.Validate(data, u.imageLimits)
+	if err != nil {
+		return nil, err
 	}
 
-	return erg.Wait()
+	// photos are shown to other users, so location and camera metadata must never leave the server
+	data, info.Format, err = imgproc.Sanitize(data, info.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	return &photoUpload{
+		data: data,
+		info: info,
+	}, nil
 }
 
-func (u *PhotoUseCase) GetPhotos(ctx context.Context, userID string) ([]*entity.Photo, error) {
+func (u *PhotoUseCase) GetPhotos(ctx context.Context, viewerID string, userID string) ([]*entity.Photo, error) {
+	err := ensureNotBlocked(ctx, u.BlockChecker, viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ensureVisible(ctx, u.BanChecker, viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ensureNotDeleted(ctx, u.DeletionChecker, viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	photos, err := u.PhotoStorage.GetPhotos(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all photos, err: %w", err)
 	}
 
+	if viewerID != userID {
+		visible := make([]*entity.Photo, 0, len(photos))
+		for _, photo := range photos {
+			if photo.Visible() {
+				visible = append(visible, photo)
+			}
+		}
+		photos = visible
+	}
+
+	err = u.urls.SignPhotos(ctx, photos...)
+	if err != nil {
+		return nil, err
+	}
+
 	return photos, nil
 }
 
-func (u *PhotoUseCase) DeletePhoto(ctx context.Context, userID string, photoID string) error {
+// GetPhotoStatus lets the owner follow an upload through processing and
+// moderation.
+func (u *PhotoUseCase) GetPhotoStatus(ctx context.Context, userID string, photoID string) (*entity.Photo, error) {
+	if _, err := strconv.ParseInt(photoID, 10, 64); err != nil {
+		return nil, apperr.WithHTTPStatus(apperr.ErrPhotoNotFound, http.StatusNotFound)
+	}
+
 	photo, err := u.PhotoStorage.GetPhoto(ctx, photoID)
 	if err != nil {
 		if errors.Is(err, apperr.ErrNoRows) {
-			return nil
+			return nil, apperr.WithHTTPStatus(apperr.ErrPhotoNotFound, http.StatusNotFound)
 		}
-		return fmt.Errorf("failed to get photo, err: %w", err)
+		return nil, fmt.Errorf("failed to get photo, err: %w", err)
 	}
 
-	err = u.PhotoStorage.DeletePhoto(ctx, userID, photoID)
+	if photo.UserID.String() != userID {
+		return nil, apperr.WithHTTPStatus(apperr.ErrPhotoNotFound, http.StatusNotFound)
+	}
+
+	return photo, nil
+}
+
+// ReorderPhotos puts the photos of the user in the given order. The list must
+// contain every photo of the user exactly once.
+func (u *PhotoUseCase) ReorderPhotos(ctx context.Context, userID string, photoIDs []int64) error {
+	err := u.TxManager.Do(ctx, func(ctx context.Context) error {
+		current, err := u.PhotoStorage.LockPhotoIDs(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		if !samePhotoIDs(current, photoIDs) {
+			return apperr.WithHTTPStatus(errors.New("photo order must list every photo of the user exactly once"), http.StatusBadRequest)
+		}
+
+		return u.PhotoStorage.SetPhotoPositions(ctx, userID, photoIDs)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to delete photo, err: %w", err)
+		return fmt.Errorf("failed to reorder photos, err: %w", err)
 	}
 
-	err = u.PhotoCloud.DeletePhoto(ctx, photo.ObjectKey)
+	return u.PhotoCache.Delete(ctx, userID)
+}
+
+// SetPrimaryPhoto moves the photo to the front, keeping the order of the rest.
+func (u *PhotoUseCase) SetPrimaryPhoto(ctx context.Context, userID string, photoID int64) error {
+	err := u.TxManager.Do(ctx, func(ctx context.Context) error {
+		current, err := u.PhotoStorage.LockPhotoIDs(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		i := slices.Index(current, photoID)
+		if i == -1 {
+			return apperr.WithHTTPStatus(apperr.ErrPhotoNotFound, http.StatusNotFound)
+		}
+
+		ordered := append([]int64{photoID}, slices.Delete(current, i, i+1)...)
+
+		return u.PhotoStorage.SetPhotoPositions(ctx, userID, ordered)
+	})
 	if err != nil {
-		errCreate := u.PhotoStorage.CreatePhoto(ctx, photo.UserID.String(), photo.URL, photo.ObjectKey)
-		if errCreate != nil {
-			return fmt.Errorf("failed to delete photo from cloud: %w, rollback failed: %w", err, errCreate)
+		return fmt.Errorf("failed to set primary photo, err: %w", err)
+	}
+
+	return u.PhotoCache.Delete(ctx, userID)
+}
+
+func samePhotoIDs(current []int64, requested []int64) bool {
+	if len(current) != len(requested) {
+		return false
+	}
+
+	sorted := slices.Clone(requested)
+	slices.Sort(sorted)
+	if len(slices.Compact(sorted)) != len(requested) {
+		return false
+	}
+
+	for _, id := range current {
+		if _, found := slices.BinarySearch(sorted, id); !found {
+			return false
 		}
-		return fmt.Errorf("failed to delete photo from cloud, rollback db delete, err: %w", err)
+	}
+
+	return true
+}
+
+func (u *PhotoUseCase) DeletePhoto(ctx context.Context, userID string, photoID string) error {
+	err := u.TxManager.Do(ctx, func(ctx context.Context) error {
+		photo, err := u.PhotoStorage.GetPhoto(ctx, photoID)
+		if err != nil {
+			return err
+		}
+
+		err = u.PhotoStorage.DeletePhoto(ctx, userID, photoID)
+		if err != nil {
+			return err
+		}
+
+		return u.EventStorage.CreateEvent(ctx, entity.EventPhotoDeleted, userID, entity.PhotoEventPayload{
+			PhotoID:     photo.ID,
+			UserID:      userID,
+			ObjectKey:   photo.ObjectKey,
+			VariantKeys: variantKeys(photo),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, apperr.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete photo, err: %w", err)
 	}
 
 	err = u.PhotoCache.Delete(ctx, userID)
@@ -131,3 +541,23 @@ func (u *PhotoUseCase) DeletePhoto(ctx context.Context, userID string, photoID s
 
 	return nil
 }
+
+// HandlePhotoDeleted removes the object of a deleted photo from the cloud. It
+// is driven by the outbox, so a failed deletion is retried instead of being
+// compensated by hand.
+func (u *PhotoUseCase) HandlePhotoDeleted(ctx context.Context, event *entity.Event) error {
+	var payload entity.PhotoEventPayload
+	err := json.Unmarshal(event.Payload, &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal %q payload, err: %w", event.Type, err)
+	}
+
+	for _, key := range append([]string{payload.ObjectKey}, payload.VariantKeys...) {
+		err = u.PhotoCloud.DeletePhoto(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to delete photo from cloud, err: %w", err)
+		}
+	}
+
+	return nil
+}
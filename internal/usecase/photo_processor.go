@@ -0,0 +1,246 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/kurochkinivan/Meet/pkg/imgproc"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+// PhotoProcessor takes staged uploads off the queue, validates and transforms
+// them and publishes the result under the user's photos.
+type PhotoProcessor struct {
+	ProcessingStorage
+	photos       *PhotoUseCase
+	workers      int
+	maxAttempts  int
+	pollInterval time.Duration
+	staleAfter   time.Duration
+	// duplicateDistance is the number of hash bits two photos may differ in
+	// to still count as the same image
+	duplicateDistance int
+}
+
+func NewPhotoProcessor(storage ProcessingStorage, photos *PhotoUseCase, workers, maxAttempts int, pollInterval, staleAfter time.Duration, duplicateDistance int) *PhotoProcessor {
+	return &PhotoProcessor{
+		ProcessingStorage: storage,
+		photos:            photos,
+		workers:           workers,
+		maxAttempts:       maxAttempts,
+		pollInterval:      pollInterval,
+		staleAfter:        staleAfter,
+		duplicateDistance: duplicateDistance,
+	}
+}
+
+type ProcessingStorage interface {
+	ClaimStagedPhoto(ctx context.Context, staleAfter time.Duration) (*entity.Photo, error)
+	CompleteProcessing(ctx context.Context, photoID int64, url string, objectKey string, blurHash string) error
+	FailProcessing(ctx context.Context, photoID int64, state entity.ProcessingState, reason string) error
+	FindDuplicate(ctx context.Context, userID string, hash uint64, maxDistance int) (int64, error)
+	SetPhotoHash(ctx context.Context, photoID int64, hash uint64, duplicateOf *int64) error
+}
+
+// Run starts the workers. Each of them drains the queue, then sleeps until the
+// next tick or until a new upload wakes it up.
+func (p *PhotoProcessor) Run(ctx context.Context) error {
+	grp, ctx := errgroup.WithContext(ctx)
+
+	for range p.workers {
+		grp.Go(func() error {
+			ticker := time.NewTicker(p.pollInterval)
+			defer ticker.Stop()
+
+			for {
+				p.drain(ctx)
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				case <-p.photos.staged:
+				}
+			}
+		})
+	}
+
+	return grp.Wait()
+}
+
+func (p *PhotoProcessor) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		photo, err := p.ProcessingStorage.ClaimStagedPhoto(ctx, p.staleAfter)
+		if err != nil {
+			if !errors.Is(err, apperr.ErrNoRows) && !errors.Is(err, context.Canceled) {
+				logrus.WithError(err).Error("failed to claim staged photo")
+			}
+			return
+		}
+
+		p.process(ctx, photo)
+	}
+}
+
+func (p *PhotoProcessor) process(ctx context.Context, photo *entity.Photo) {
+	log := logrus.WithFields(logrus.Fields{
+		"photo_id": photo.ID,
+		"user_id":  photo.UserID,
+		"attempt":  photo.ProcessingAttempts,
+	})
+	stagingKey := photo.ObjectKey
+
+	err := p.publish(ctx, photo)
+	switch {
+	case err == nil:
+		p.removeStaging(ctx, stagingKey)
+
+		err = p.photos.PhotoCache.Delete(ctx, photo.UserID.String())
+		if err != nil {
+			log.WithError(err).Error("failed to evict user from cache")
+		}
+	case errors.Is(err, apperr.ErrNoRows):
+		// the photo was deleted while being processed, its staging object
+		// goes away with the photo.deleted event
+		log.Info("photo deleted during processing")
+	case isInvalidImage(err) || photo.ProcessingAttempts >= p.maxAttempts:
+		log.WithError(err).Warn("photo processing failed")
+
+		err = p.ProcessingStorage.FailProcessing(ctx, photo.ID, entity.ProcessingFailed, err.Error())
+		if err != nil {
+			log.WithError(err).Error("failed to mark photo as failed")
+			return
+		}
+		p.removeStaging(ctx, stagingKey)
+	default:
+		log.WithError(err).Warn("photo processing failed, will retry")
+
+		err = p.ProcessingStorage.FailProcessing(ctx, photo.ID, entity.ProcessingQueued, err.Error())
+		if err != nil {
+			log.WithError(err).Error("failed to requeue photo")
+		}
+	}
+}
+
+// publish uploads the processed photo with its variants and records them.
+// Uploaded objects are removed if the records can't be updated.
+func (p *PhotoProcessor) publish(ctx context.Context, staged *entity.Photo) error {
+	data, err := p.photos.PhotoCloud.DownloadPhoto(ctx, staged.ObjectKey)
+	if err != nil {
+		return fmt.Errorf("failed to download staged photo, err: %w", err)
+	}
+
+	upload, err := p.photos.preparePhoto(data)
+	if err != nil {
+		return err
+	}
+
+	// HEIC can't be decoded here, such photos are not checked for duplicates
+	// and get no placeholder
+	hash, err := imgproc.DHash(upload.data, upload.info.Format)
+	hashed := err == nil
+	if err != nil && !errors.Is(err, imgproc.ErrUnsupportedFormat) {
+		return err
+	}
+
+	blurHash, err := imgproc.BlurHash(upload.data, upload.info.Format)
+	if err != nil && !errors.Is(err, imgproc.ErrUnsupportedFormat) {
+		return err
+	}
+
+	userID := staged.UserID.String()
+	url, objectKey, err := p.photos.PhotoCloud.UploadPhoto(ctx, userID, bytes.NewReader(upload.data), upload.info.Format)
+	if err != nil {
+		return fmt.Errorf("failed to upload photo, err: %w", err)
+	}
+
+	photo := &entity.Photo{ObjectKey: objectKey}
+
+	err = p.photos.uploadVariants(ctx, photo, upload)
+	if err == nil {
+		err = p.photos.TxManager.Do(ctx, func(ctx context.Context) error {
+			err := p.ProcessingStorage.CompleteProcessing(ctx, staged.ID, url, objectKey, blurHash)
+			if err != nil {
+				return err
+			}
+
+			for _, variant := range photo.Variants {
+				err = p.photos.PhotoStorage.CreatePhotoVariant(ctx, staged.ID, variant)
+				if err != nil {
+					return err
+				}
+			}
+
+			if hashed {
+				err = p.recordHash(ctx, staged.ID, userID, hash)
+				if err != nil {
+					return err
+				}
+			}
+
+			return p.photos.EventStorage.CreateEvent(ctx, entity.EventPhotoCreated, userID, entity.PhotoEventPayload{
+				PhotoID:     staged.ID,
+				UserID:      userID,
+				ObjectKey:   objectKey,
+				VariantKeys: variantKeys(photo),
+			})
+		})
+	}
+	if err != nil {
+		var errDelete error
+		for _, key := range photo.ObjectKeys() {
+			errDelete = errors.Join(errDelete, p.photos.PhotoCloud.DeletePhoto(ctx, key))
+		}
+		if errDelete != nil {
+			return fmt.Errorf("failed to publish photo: %w; rollback failed: %v", err, errDelete)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// recordHash stores the hash of the photo and flags it for moderation if the
+// same image is already used by another account.
+func (p *PhotoProcessor) recordHash(ctx context.Context, photoID int64, userID string, hash uint64) error {
+	clusterID, err := p.ProcessingStorage.FindDuplicate(ctx, userID, hash, p.duplicateDistance)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNoRows) {
+			return p.ProcessingStorage.SetPhotoHash(ctx, photoID, hash, nil)
+		}
+		return err
+	}
+
+	err = p.ProcessingStorage.SetPhotoHash(ctx, photoID, hash, &clusterID)
+	if err != nil {
+		return err
+	}
+
+	return p.photos.EventStorage.CreateEvent(ctx, entity.EventPhotoDuplicate, userID, entity.PhotoEventPayload{
+		PhotoID:     photoID,
+		UserID:      userID,
+		DuplicateOf: clusterID,
+	})
+}
+
+func (p *PhotoProcessor) removeStaging(ctx context.Context, stagingKey string) {
+	err := p.photos.PhotoCloud.DeletePhoto(ctx, stagingKey)
+	if err != nil {
+		logrus.WithError(err).WithField("objectKey", stagingKey).Error("failed to delete staged photo")
+	}
+}
+
+// isInvalidImage tells apart uploads that will never succeed from errors that
+// are worth another attempt.
+func isInvalidImage(err error) bool {
+	return errors.Is(err, imgproc.ErrUnsupportedFormat) ||
+		errors.Is(err, imgproc.ErrFileTooLarge) ||
+		errors.Is(err, imgproc.ErrDimensions) ||
+		errors.Is(err, imgproc.ErrCorrupted)
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/sirupsen/logrus"
+)
+
+// PhotoURLSigner fills in photo urls at read time. Photos are private, the
+// urls stored with them only tell where an object is and are never handed out.
+type PhotoURLSigner struct {
+	PhotoURLStorage
+	PhotoURLCache
+	cacheTTL time.Duration
+}
+
+// NewPhotoURLSigner caches signed urls for cacheTTL, which has to be shorter
+// than their expiry so that clients always get some time to load the photo.
+func NewPhotoURLSigner(storage PhotoURLStorage, cache PhotoURLCache, cacheTTL time.Duration) *PhotoURLSigner {
+	return &PhotoURLSigner{
+		PhotoURLStorage: storage,
+		PhotoURLCache:   cache,
+		cacheTTL:        cacheTTL,
+	}
+}
+
+type PhotoURLStorage interface {
+	SignPhotoURL(ctx context.Context, objectKey string) (string, error)
+}
+
+type PhotoURLCache interface {
+	GetPhotoURLs(ctx context.Context, objectKeys []string) (map[string]string, error)
+	SetPhotoURL(ctx context.Context, objectKey string, url string, ttl time.Duration) error
+}
+
+// SignPhotos replaces the urls of processed photos and their variants with
+// signed ones. Photos still in processing have nothing to show yet.
+func (s *PhotoURLSigner) SignPhotos(ctx context.Context, photos ...*entity.Photo) error {
+	keys := make([]string, 0, len(photos))
+	for _, photo := range photos {
+		if photo.Processing == entity.ProcessingReady {
+			keys = append(keys, photo.ObjectKeys()...)
+		}
+	}
+
+	urls, err := s.PhotoURLCache.GetPhotoURLs(ctx, keys)
+	if err != nil {
+		logrus.WithError(err).Error("failed to get signed photo urls from cache")
+		urls = make(map[string]string, len(keys))
+	}
+
+	for _, key := range keys {
+		if _, ok := urls[key]; ok {
+			continue
+		}
+
+		url, err := s.PhotoURLStorage.SignPhotoURL(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to sign photo url, err: %w", err)
+		}
+		urls[key] = url
+
+		err = s.PhotoURLCache.SetPhotoURL(ctx, key, url, s.cacheTTL)
+		if err != nil {
+			logrus.WithError(err).Error("failed to cache signed photo url")
+		}
+	}
+
+	for _, photo := range photos {
+		if photo.Processing != entity.ProcessingReady {
+			photo.URL = ""
+			continue
+		}
+
+		photo.URL = urls[photo.ObjectKey]
+		for _, variant := range photo.Variants {
+			variant.URL = urls[variant.ObjectKey]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+type PushUseCase struct {
+	DeviceStorage
+	PushSender
+	queue         chan pushJob
+	workers       int
+	maxRetries    int
+	retryInterval time.Duration
+}
+
+func NewPushUseCase(storage DeviceStorage, sender PushSender, workers, queueSize, maxRetries int, retryInterval time.Duration) *PushUseCase {
+	return &PushUseCase{
+		DeviceStorage: storage,
+		PushSender:    sender,
+		queue:         make(chan pushJob, queueSize),
+		workers:       workers,
+		maxRetries:    maxRetries,
+		retryInterval: retryInterval,
+	}
+}
+
+type DeviceStorage interface {
+	UpsertDeviceToken(ctx context.Context, userID string, token string, platform entity.Platform) error
+	GetDeviceTokens(ctx context.Context, userID string) ([]*entity.DeviceToken, error)
+	DeleteDeviceToken(ctx context.Context, userID string, token string) error
+	DeleteDeviceTokens(ctx context.Context, tokens []string) error
+}
+
+// PushSender delivers a single push to a device. Implementations must return
+// apperr.ErrInvalidDeviceToken when the provider reports the token as
+// unregistered, so that it gets pruned.
+type PushSender interface {
+	Send(ctx context.Context, token *entity.DeviceToken, message *entity.PushMessage) error
+}
+
+type pushJob struct {
+	userID  string
+	message *entity.PushMessage
+}
+
+func (u *PushUseCase) RegisterDevice(ctx context.Context, userID string, token string, platform entity.Platform) error {
+	if token == "" {
+		return apperr.WithHTTPStatus(errors.New("device token must be provided"), http.StatusBadRequest)
+	}
+
+	if !platform.Valid() {
+		return apperr.WithHTTPStatus(fmt.Errorf("%w: %q", apperr.ErrUnknownPlatform, platform), http.StatusBadRequest)
+	}
+
+	err := u.DeviceStorage.UpsertDeviceToken(ctx, userID, token, platform)
+	if err != nil {
+		return fmt.Errorf("failed to register device token, err: %w", err)
+	}
+
+	return nil
+}
+
+func (u *PushUseCase) UnregisterDevice(ctx context.Context, userID string, token string) error {
+	err := u.DeviceStorage.DeleteDeviceToken(ctx, userID, token)
+	if err != nil {
+		return fmt.Errorf("failed to unregister device token, err: %w", err)
+	}
+
+	return nil
+}
+
+// Push enqueues a push for every device of the user. It never blocks: when the
+// queue is full the push is dropped, the in-app notification is still stored.
+func (u *PushUseCase) Push(userID string, message *entity.PushMessage) {
+	select {
+	case u.queue <- pushJob{userID: userID, message: message}:
+	default:
+		logrus.WithField("user_id", userID).Warn("push queue is full, dropping push notification")
+	}
+}
+
+func (u *PushUseCase) Run(ctx context.Context) error {
+	grp, ctx := errgroup.WithContext(ctx)
+
+	for range u.workers {
+		grp.Go(func() error {
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case job := <-u.queue:
+					u.dispatch(ctx, job)
+				}
+			}
+		})
+	}
+
+	return grp.Wait()
+}
+
+func (u *PushUseCase) dispatch(ctx context.Context, job pushJob) {
+	tokens, err := u.DeviceStorage.GetDeviceTokens(ctx, job.userID)
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to get device tokens for user %q", job.userID)
+		return
+	}
+
+	invalid := make([]string, 0)
+	for _, token := range tokens {
+		err = u.sendWithRetries(ctx, token, job.message)
+		if err != nil {
+			if errors.Is(err, apperr.ErrInvalidDeviceToken) {
+				invalid = append(invalid, token.Token)
+				continue
+			}
+			logrus.WithError(err).WithField("platform", token.Platform).Errorf("failed to send push to user %q", job.userID)
+		}
+	}
+
+	err = u.DeviceStorage.DeleteDeviceTokens(ctx, invalid)
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to prune invalid device tokens for user %q", job.userID)
+	}
+}
+
+func (u *PushUseCase) sendWithRetries(ctx context.Context, token *entity.DeviceToken, message *entity.PushMessage) error {
+	var err error
+	for attempt := range u.maxRetries + 1 {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(u.retryInterval * time.Duration(attempt)):
+			}
+		}
+
+		err = u.PushSender.Send(ctx, token, message)
+		if err == nil || errors.Is(err, apperr.ErrInvalidDeviceToken) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("all %d attempts failed, err: %w", u.maxRetries+1, err)
+}
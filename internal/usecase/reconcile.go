@@ -0,0 +1,229 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/sirupsen/logrus"
+)
+
+// Reconciler finds where the photos table and the blob storage drifted apart,
+// which happens when the compensation of a failed upload or deletion fails
+// itself, and optionally fixes it.
+type Reconciler struct {
+	ReconcileStorage
+	ObjectLister
+	ObjectACL
+	photos   *PhotoUseCase
+	interval time.Duration
+	minAge   time.Duration
+	dryRun   bool
+}
+
+// NewReconciler leaves alone objects younger than minAge, they may belong to
+// an upload that is still in flight.
+func NewReconciler(storage ReconcileStorage, lister ObjectLister, acl ObjectACL, photos *PhotoUseCase, interval, minAge time.Duration, dryRun bool) *Reconciler {
+	return &Reconciler{
+		ReconcileStorage: storage,
+		ObjectLister:     lister,
+		ObjectACL:        acl,
+		photos:           photos,
+		interval:         interval,
+		minAge:           minAge,
+		dryRun:           dryRun,
+	}
+}
+
+type ReconcileStorage interface {
+	GetAllPhotos(ctx context.Context) ([]*entity.Photo, error)
+	DeletePhotoVariant(ctx context.Context, photoID int64, width int) error
+}
+
+type ObjectLister interface {
+	ListObjects(ctx context.Context, prefix string) ([]*entity.StoredObject, error)
+}
+
+type ObjectACL interface {
+	MakePrivate(ctx context.Context, objectKey string) error
+}
+
+// prefixes the reconciler looks at, nothing else in the bucket is touched
+const (
+	photosPrefix  = "users/"
+	stagingPrefix = "staging/users/"
+)
+
+// Run reconciles once per interval with the dry run mode from the config.
+func (r *Reconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			_, err := r.Reconcile(ctx, r.dryRun)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				logrus.WithError(err).Error("failed to reconcile photos")
+			}
+		}
+	}
+}
+
+// Reconcile compares the photos with the stored objects. Orphan objects are
+// deleted, photos whose original is gone are deleted like the user would
+// delete them, and variants whose object is gone are forgotten. In dry run
+// mode the findings are only reported.
+func (r *Reconciler) Reconcile(ctx context.Context, dryRun bool) (*entity.ReconcileReport, error) {
+	// photos are read before objects are listed: a photo is recorded only
+	// after its objects are uploaded, so every photo read here has its
+	// objects in the listing unless they are really gone
+	photos, err := r.ReconcileStorage.GetAllPhotos(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get photos, err: %w", err)
+	}
+
+	known := make(map[string]bool)
+	for _, photo := range photos {
+		for _, key := range photo.ObjectKeys() {
+			known[key] = true
+		}
+	}
+
+	report := &entity.ReconcileReport{DryRun: dryRun}
+	stored := make(map[string]bool)
+	cutoff := time.Now().Add(-r.minAge)
+
+	for _, prefix := range []string{photosPrefix, stagingPrefix} {
+		objects, err := r.ObjectLister.ListObjects(ctx, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects, err: %w", err)
+		}
+
+		for _, object := range objects {
+			if prefix == photosPrefix && !isPhotoKey(object.Key) {
+				continue
+			}
+
+			stored[object.Key] = true
+			if !known[object.Key] && object.LastModified.Before(cutoff) {
+				report.OrphanObjects = append(report.OrphanObjects, object.Key)
+			}
+		}
+	}
+
+	var errs []error
+	for _, key := range report.OrphanObjects {
+		logrus.WithFields(logrus.Fields{"objectKey": key, "dry_run": dryRun}).Warn("orphan photo object")
+		if dryRun {
+			continue
+		}
+
+		err = r.photos.PhotoCloud.DeletePhoto(ctx, key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete orphan object %s, err: %w", key, err))
+		}
+	}
+
+	for _, photo := range photos {
+		// photos in processing point at the staging area, the processor takes
+		// care of them
+		if photo.Processing != entity.ProcessingReady {
+			continue
+		}
+
+		log := logrus.WithFields(logrus.Fields{
+			"photo_id": photo.ID,
+			"user_id":  photo.UserID,
+			"dry_run":  dryRun,
+		})
+
+		if !stored[photo.ObjectKey] {
+			report.DanglingPhotos = append(report.DanglingPhotos, photo.ID)
+			log.WithField("objectKey", photo.ObjectKey).Warn("photo object is missing")
+			if dryRun {
+				continue
+			}
+
+			// deleting the photo the regular way also removes what's left of
+			// its variants
+			err = r.photos.DeletePhoto(ctx, photo.UserID.String(), strconv.FormatInt(photo.ID, 10))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete dangling photo %d, err: %w", photo.ID, err))
+			}
+			continue
+		}
+
+		for _, variant := range photo.Variants {
+			if stored[variant.ObjectKey] {
+				continue
+			}
+
+			report.DanglingVariants = append(report.DanglingVariants, variant.ObjectKey)
+			log.WithField("objectKey", variant.ObjectKey).Warn("photo variant object is missing")
+			if dryRun {
+				continue
+			}
+
+			err = r.ReconcileStorage.DeletePhotoVariant(ctx, photo.ID, variant.Width)
+			if err == nil {
+				err = r.photos.PhotoCache.Delete(ctx, photo.UserID.String())
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete dangling variant %s, err: %w", variant.ObjectKey, err))
+			}
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"orphan_objects":    len(report.OrphanObjects),
+		"dangling_photos":   len(report.DanglingPhotos),
+		"dangling_variants": len(report.DanglingVariants),
+		"dry_run":           dryRun,
+	}).Info("photos reconciled")
+
+	return report, errors.Join(errs...)
+}
+
+// ResetACLs makes every photo and staging object private. Objects uploaded
+// before photo urls were signed were public-read, and stay readable by anyone
+// who kept their url until this runs once. It returns the number of objects
+// that were reset.
+func (r *Reconciler) ResetACLs(ctx context.Context) (int, error) {
+	var reset int
+	var errs []error
+	for _, prefix := range []string{photosPrefix, stagingPrefix} {
+		objects, err := r.ObjectLister.ListObjects(ctx, prefix)
+		if err != nil {
+			return reset, fmt.Errorf("failed to list objects, err: %w", err)
+		}
+
+		for _, object := range objects {
+			err = r.ObjectACL.MakePrivate(ctx, object.Key)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			reset++
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"objects": reset,
+		"failed":  len(errs),
+	}).Info("photo object acls reset")
+
+	return reset, errors.Join(errs...)
+}
+
+// isPhotoKey matches users/<id>/photos/<name>.
+func isPhotoKey(key string) bool {
+	parts := strings.Split(key, "/")
+	return len(parts) == 4 && parts[0] == "users" && parts[2] == "photos" && parts[3] != ""
+}
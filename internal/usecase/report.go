@@ -0,0 +1,226 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+const maxReportCommentLength = 1000
+
+type ReportUseCase struct {
+	ReportStorage
+	ProfileCache
+	TxManager
+	autoHideDuration  time.Duration
+	autoHideReporters int
+}
+
+func NewReportUseCase(storage ReportStorage, cache ProfileCache, txManager TxManager, autoHideDuration time.Duration, autoHideReporters int) *ReportUseCase {
+	return &ReportUseCase{
+		ReportStorage:     storage,
+		ProfileCache:      cache,
+		TxManager:         txManager,
+		autoHideDuration:  autoHideDuration,
+		autoHideReporters: autoHideReporters,
+	}
+}
+
+type ReportStorage interface {
+	CreateReport(ctx context.Context, report *entity.Report) (*entity.Report, error)
+	CreateSystemReport(ctx context.Context, report *entity.Report) error
+	GetReportQueue(ctx context.Context, status entity.ReportStatus, limit, offset uint64) ([]*entity.Report, error)
+	UpdateReportStatus(ctx context.Context, reportID string, status entity.ReportStatus) (entity.ReportTargetType, string, error)
+	TargetExists(ctx context.Context, targetType entity.ReportTargetType, targetID string) (bool, error)
+	CountHidingReporters(ctx context.Context, targetType entity.ReportTargetType, targetID string) (int, error)
+	HideUser(ctx context.Context, userID string, until time.Time) error
+	HidePhoto(ctx context.Context, photoID string, until time.Time) (string, error)
+	UnhideUser(ctx context.Context, userID string) error
+	UnhidePhoto(ctx context.Context, photoID string) (string, error)
+}
+
+type ProfileCache interface {
+	Delete(ctx context.Context, userID string) error
+}
+
+// Report files a report from reporterID. The target is hidden until a moderator
+// reviews it once autoHideReporters distinct users reported it for a hiding
+// reason, so a single user cannot take a profile down on their own.
+func (u *ReportUseCase) Report(ctx context.Context, reporterID string, report *entity.Report) (*entity.Report, error) {
+	err := validateReport(report)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(err, http.StatusBadRequest)
+	}
+
+	reporter, err := uuid.Parse(reporterID)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(fmt.Errorf("%w: invalid reporter id", apperr.ErrInvalidReport), http.StatusBadRequest)
+	}
+	report.ReporterID = uuid.NullUUID{UUID: reporter, Valid: true}
+
+	var hiddenUserID string
+	err = u.TxManager.Do(ctx, func(ctx context.Context) error {
+		exists, err := u.ReportStorage.TargetExists(ctx, report.TargetType, report.TargetID)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return apperr.WithHTTPStatus(apperr.ErrReportTargetNotFound, http.StatusNotFound)
+		}
+
+		report, err = u.ReportStorage.CreateReport(ctx, report)
+		if err != nil {
+			return err
+		}
+
+		// a repeated report updates the existing row but keeps its status, so
+		// reports a moderator already resolved never hide the target again
+		if !report.Reason.HidesTarget() || !report.Status.Unresolved() {
+			return nil
+		}
+
+		reporters, err := u.ReportStorage.CountHidingReporters(ctx, report.TargetType, report.TargetID)
+		if err != nil {
+			return err
+		}
+
+		if reporters < u.autoHideReporters {
+			return nil
+		}
+
+		until := time.Now().Add(u.autoHideDuration)
+		switch report.TargetType {
+		case entity.ReportTargetUser:
+			hiddenUserID = report.TargetID
+			return u.ReportStorage.HideUser(ctx, report.TargetID, until)
+		case entity.ReportTargetPhoto:
+			hiddenUserID, err = u.ReportStorage.HidePhoto(ctx, report.TargetID, until)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report, err: %w", err)
+	}
+
+	if hiddenUserID != "" {
+		err = u.ProfileCache.Delete(ctx, hiddenUserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// HandlePhotoDuplicate puts a photo that another account already uses into
+// the report queue, where moderators look into catfishing.
+func (u *ReportUseCase) HandlePhotoDuplicate(ctx context.Context, event *entity.Event) error {
+	var payload entity.PhotoEventPayload
+	err := json.Unmarshal(event.Payload, &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal %q payload, err: %w", event.Type, err)
+	}
+
+	err = u.ReportStorage.CreateSystemReport(ctx, &entity.Report{
+		TargetType: entity.ReportTargetPhoto,
+		TargetID:   strconv.FormatInt(payload.PhotoID, 10),
+		Reason:     entity.ReportReasonFakeProfile,
+		Comment:    fmt.Sprintf("near duplicate of photo %d of another account", payload.DuplicateOf),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to report duplicate photo, err: %w", err)
+	}
+
+	return nil
+}
+
+func (u *ReportUseCase) GetReportQueue(ctx context.Context, status entity.ReportStatus, limit, offset uint64) ([]*entity.Report, error) {
+	if !status.Valid() {
+		return nil, apperr.WithHTTPStatus(fmt.Errorf("%w: unknown status %q", apperr.ErrInvalidReport, status), http.StatusBadRequest)
+	}
+
+	reports, err := u.ReportStorage.GetReportQueue(ctx, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report queue, err: %w", err)
+	}
+
+	return reports, nil
+}
+
+// UpdateReportStatus resolves the reports against the target of the report.
+// Dismissing them lifts the automatic hiding of the target, it returns the id
+// of the user whose profile changed so that the caller can evict it from the
+// cache once the transaction is committed.
+func (u *ReportUseCase) UpdateReportStatus(ctx context.Context, reportID string, status entity.ReportStatus) (string, error) {
+	if !status.Valid() {
+		return "", apperr.WithHTTPStatus(fmt.Errorf("%w: unknown status %q", apperr.ErrInvalidReport, status), http.StatusBadRequest)
+	}
+
+	if _, err := strconv.ParseInt(reportID, 10, 64); err != nil {
+		return "", apperr.WithHTTPStatus(apperr.ErrNoRows, http.StatusNotFound)
+	}
+
+	targetType, targetID, err := u.ReportStorage.UpdateReportStatus(ctx, reportID, status)
+	if err != nil {
+		return "", fmt.Errorf("failed to update report status, err: %w", err)
+	}
+
+	if status != entity.ReportStatusDismissed {
+		return "", nil
+	}
+
+	var unhiddenUserID string
+	switch targetType {
+	case entity.ReportTargetUser:
+		unhiddenUserID = targetID
+		err = u.ReportStorage.UnhideUser(ctx, targetID)
+	case entity.ReportTargetPhoto:
+		unhiddenUserID, err = u.ReportStorage.UnhidePhoto(ctx, targetID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to unhide report target, err: %w", err)
+	}
+
+	return unhiddenUserID, nil
+}
+
+func validateReport(report *entity.Report) error {
+	if !report.TargetType.Valid() {
+		return fmt.Errorf("%w: unknown target type %q", apperr.ErrInvalidReport, report.TargetType)
+	}
+
+	if !report.Reason.Valid() {
+		return fmt.Errorf("%w: unknown reason %q", apperr.ErrInvalidReport, report.Reason)
+	}
+
+	if len(report.Comment) > maxReportCommentLength {
+		return fmt.Errorf("%w: comment is longer than %d characters", apperr.ErrInvalidReport, maxReportCommentLength)
+	}
+
+	switch report.TargetType {
+	case entity.ReportTargetUser:
+		if _, err := uuid.Parse(report.TargetID); err != nil {
+			return fmt.Errorf("%w: invalid user id %q", apperr.ErrInvalidReport, report.TargetID)
+		}
+	case entity.ReportTargetPhoto:
+		if _, err := strconv.ParseInt(report.TargetID, 10, 64); err != nil {
+			return fmt.Errorf("%w: invalid photo id %q", apperr.ErrInvalidReport, report.TargetID)
+		}
+	case entity.ReportTargetMessage:
+		if report.TargetID == "" {
+			return fmt.Errorf("%w: empty message id", apperr.ErrInvalidReport)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+)
+
+type fakeTxManager struct{}
+
+func (fakeTxManager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type fakeProfileCache struct {
+	deleted []string
+}
+
+func (c *fakeProfileCache) Delete(ctx context.Context, userID string) error {
+	c.deleted = append(c.deleted, userID)
+	return nil
+}
+
+type fakeAuditStorage struct{}
+
+func (fakeAuditStorage) CreateAuditEntry(ctx context.Context, entry *entity.AuditEntry) error {
+	return nil
+}
+
+// fakeReportStorage keeps a single report and the hiding of its target.
+type fakeReportStorage struct {
+	ReportStorage
+	report      *entity.Report
+	photoOwner  string
+	hiddenUntil *time.Time
+}
+
+func (s *fakeReportStorage) UpdateReportStatus(ctx context.Context, reportID string, status entity.ReportStatus) (entity.ReportTargetType, string, error) {
+	s.report.Status = status
+	return s.report.TargetType, s.report.TargetID, nil
+}
+
+func (s *fakeReportStorage) UnhideUser(ctx context.Context, userID string) error {
+	s.hiddenUntil = nil
+	return nil
+}
+
+func (s *fakeReportStorage) UnhidePhoto(ctx context.Context, photoID string) (string, error) {
+	s.hiddenUntil = nil
+	return s.photoOwner, nil
+}
+
+func TestUpdateReportStatusUnhidesDismissedTarget(t *testing.T) {
+	ownerID := uuid.NewString()
+
+	tests := []struct {
+		name        string
+		target      entity.ReportTargetType
+		targetID    string
+		status      entity.ReportStatus
+		wantHidden  bool
+		wantEvicted []string
+	}{
+		{
+			name:        "dismissed user",
+			target:      entity.ReportTargetUser,
+			targetID:    ownerID,
+			status:      entity.ReportStatusDismissed,
+			wantEvicted: []string{ownerID},
+		},
+		{
+			name:        "dismissed photo",
+			target:      entity.ReportTargetPhoto,
+			targetID:    "42",
+			status:      entity.ReportStatusDismissed,
+			wantEvicted: []string{ownerID},
+		},
+		{
+			name:       "actioned user",
+			target:     entity.ReportTargetUser,
+			targetID:   ownerID,
+			status:     entity.ReportStatusActioned,
+			wantHidden: true,
+		},
+		{
+			name:       "reviewing photo",
+			target:     entity.ReportTargetPhoto,
+			targetID:   "42",
+			status:     entity.ReportStatusReviewing,
+			wantHidden: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hiddenUntil := time.Now().Add(24 * time.Hour)
+			storage := &fakeReportStorage{
+				report: &entity.Report{
+					TargetType: tt.target,
+					TargetID:   tt.targetID,
+					Reason:     entity.ReportReasonUnderage,
+					Status:     entity.ReportStatusOpen,
+				},
+				photoOwner:  ownerID,
+				hiddenUntil: &hiddenUntil,
+			}
+			cache := &fakeProfileCache{}
+			reports := NewReportUseCase(storage, cache, fakeTxManager{}, 24*time.Hour, 3)
+			admin := NewAdminUseCase(nil, nil, nil, nil, fakeAuditStorage{}, nil, cache, fakeTxManager{}, nil, reports)
+
+			err := admin.UpdateReportStatus(context.Background(), uuid.NewString(), "1", tt.status)
+			if err != nil {
+				t.Fatalf("UpdateReportStatus() error = %v", err)
+			}
+
+			if hidden := storage.hiddenUntil != nil; hidden != tt.wantHidden {
+				t.Errorf("target hidden = %v, want %v", hidden, tt.wantHidden)
+			}
+			if !slices.Equal(cache.deleted, tt.wantEvicted) {
+				t.Errorf("evicted profiles = %v, want %v", cache.deleted, tt.wantEvicted)
+			}
+		})
+	}
+}
+
+func TestUpdateReportStatusRejectsUnknownStatus(t *testing.T) {
+	storage := &fakeReportStorage{report: &entity.Report{}}
+	reports := NewReportUseCase(storage, &fakeProfileCache{}, fakeTxManager{}, 24*time.Hour, 3)
+
+	_, err := reports.UpdateReportStatus(context.Background(), "1", "closed")
+	if apperr.HTTPStatus(err) != http.StatusBadRequest {
+		t.Fatalf("UpdateReportStatus() status = %d, want %d", apperr.HTTPStatus(err), http.StatusBadRequest)
+	}
+}
+
+func TestUpdateReportStatusRejectsMalformedID(t *testing.T) {
+	storage := &fakeReportStorage{report: &entity.Report{}}
+	reports := NewReportUseCase(storage, &fakeProfileCache{}, fakeTxManager{}, 24*time.Hour, 3)
+
+	_, err := reports.UpdateReportStatus(context.Background(), "1 OR 1=1", entity.ReportStatusDismissed)
+	if apperr.HTTPStatus(err) != http.StatusNotFound {
+		t.Fatalf("UpdateReportStatus() status = %d, want %d", apperr.HTTPStatus(err), http.StatusNotFound)
+	}
+	if storage.report.Status != "" {
+		t.Errorf("report status = %q, want it untouched", storage.report.Status)
+	}
+}
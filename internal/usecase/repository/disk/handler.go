@@ -0,0 +1,82 @@
+package disk
+
+import (
+	"crypto/hmac"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServeHTTP serves GET and takes PUT requests for urls signed by the
+// repository. It expects the path to be the object key, so it has to be
+// mounted behind http.StripPrefix.
+func (r *PhotoRepository) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	objectKey := strings.TrimPrefix(req.URL.Path, "/")
+	if objectKey == "" || path.Clean("/"+objectKey) != "/"+objectKey {
+		http.NotFound(w, req)
+		return
+	}
+
+	var contentType string
+	var size int64
+	if req.Method == http.MethodPut {
+		contentType = req.Header.Get("Content-Type")
+		size = req.ContentLength
+	}
+
+	if !r.verify(req.Method, objectKey, req.URL.Query().Get("expires"), req.URL.Query().Get("signature"), contentType, size) {
+		http.Error(w, "invalid or expired signature", http.StatusForbidden)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		r.serveObject(w, req, objectKey)
+	case http.MethodPut:
+		err := r.write(objectKey, io.LimitReader(req.Body, size))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *PhotoRepository) serveObject(w http.ResponseWriter, req *http.Request, objectKey string) {
+	f, err := os.Open(r.path(objectKey))
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, req)
+		return
+	}
+
+	http.ServeContent(w, req, info.Name(), info.ModTime(), f)
+}
+
+func (r *PhotoRepository) verify(method string, objectKey string, expires string, signature string, contentType string, size int64) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+
+	// HEAD is allowed wherever GET is
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+
+	expected := r.sign(method, objectKey, expires, contentType, size)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,242 @@
+package disk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/kurochkinivan/Meet/pkg/imgproc"
+)
+
+// PhotoRepository keeps photos on the local disk under the same keys the s3
+// repository uses. Objects are served by the repository itself through urls
+// signed with secret, see ServeHTTP.
+type PhotoRepository struct {
+	root          string
+	baseURL       string
+	secret        []byte
+	presignExpiry time.Duration
+	urlExpiry     time.Duration
+}
+
+func NewPhotoRepository(root string, baseURL string, secret string, presignExpiry, urlExpiry time.Duration) *PhotoRepository {
+	return &PhotoRepository{
+		root:          root,
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		secret:        []byte(secret),
+		presignExpiry: presignExpiry,
+		urlExpiry:     urlExpiry,
+	}
+}
+
+func (r *PhotoRepository) UploadPhoto(ctx context.Context, userID string, file io.Reader, format imgproc.Format) (url, objectKey string, err error) {
+	objectKey = fmt.Sprintf("users/%s/photos/%s%s", userID, uuid.New().String(), format.Extension())
+
+	err = r.write(objectKey, file)
+	if err != nil {
+		return "", "", err
+	}
+
+	return r.objectURL(objectKey), objectKey, nil
+}
+
+// UploadVariant stores a resized copy next to the original, the key gets the
+// width as a suffix: users/<id>/photos/<uuid>_480.jpg.
+func (r *PhotoRepository) UploadVariant(ctx context.Context, originalKey string, width int, file io.Reader, format imgproc.Format) (url, objectKey string, err error) {
+	objectKey = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(originalKey, path.Ext(originalKey)), width, format.Extension())
+
+	err = r.write(objectKey, file)
+	if err != nil {
+		return "", "", err
+	}
+
+	return r.objectURL(objectKey), objectKey, nil
+}
+
+func (r *PhotoRepository) UploadStaging(ctx context.Context, userID string, file io.Reader) (objectKey string, err error) {
+	objectKey = stagingKey(userID, uuid.New().String())
+
+	err = r.write(objectKey, file)
+	if err != nil {
+		return "", err
+	}
+
+	return objectKey, nil
+}
+
+// PresignStaging signs a PUT of exactly size bytes of contentType to
+// ServeHTTP, mirroring the presigned s3 uploads.
+func (r *PhotoRepository) PresignStaging(ctx context.Context, userID string, contentType string, size int64) (*entity.PresignedUpload, error) {
+	uploadID := uuid.New().String()
+	objectKey := stagingKey(userID, uploadID)
+	expiresAt := time.Now().Add(r.presignExpiry)
+
+	return &entity.PresignedUpload{
+		ID:     uploadID,
+		URL:    r.signedURL(http.MethodPut, objectKey, expiresAt, contentType, size),
+		Method: http.MethodPut,
+		Headers: map[string]string{
+			"Content-Type":   contentType,
+			"Content-Length": strconv.FormatInt(size, 10),
+		},
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// HeadStaging checks that a direct upload has arrived and returns its key and
+// size. It returns apperr.ErrNoRows if there is no such object.
+func (r *PhotoRepository) HeadStaging(ctx context.Context, userID string, uploadID string) (objectKey string, size int64, err error) {
+	objectKey = stagingKey(userID, uploadID)
+
+	info, err := os.Stat(r.path(objectKey))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", 0, apperr.ErrNoRows
+		}
+		return "", 0, apperr.WithHTTPStatus(fmt.Errorf("can't stat object %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+
+	return objectKey, info.Size(), nil
+}
+
+func (r *PhotoRepository) DownloadPhoto(ctx context.Context, objectKey string) ([]byte, error) {
+	data, err := os.ReadFile(r.path(objectKey))
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(fmt.Errorf("can't read object %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+
+	return data, nil
+}
+
+func (r *PhotoRepository) SignPhotoURL(ctx context.Context, objectKey string) (string, error) {
+	return r.signedURL(http.MethodGet, objectKey, time.Now().Add(r.urlExpiry), "", 0), nil
+}
+
+func (r *PhotoRepository) DeletePhoto(ctx context.Context, objectKey string) error {
+	err := os.Remove(r.path(objectKey))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return apperr.WithHTTPStatus(fmt.Errorf("can't delete object %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// MakePrivate does nothing, objects on disk are only served through signed
+// urls.
+func (r *PhotoRepository) MakePrivate(ctx context.Context, objectKey string) error {
+	return nil
+}
+
+// ListObjects returns every object under the prefix.
+func (r *PhotoRepository) ListObjects(ctx context.Context, prefix string) ([]*entity.StoredObject, error) {
+	objects := make([]*entity.StoredObject, 0)
+
+	err := filepath.WalkDir(r.path(prefix), func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(r.root, name)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, &entity.StoredObject{
+			Key:          filepath.ToSlash(rel),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(fmt.Errorf("can't list objects with prefix %s, err: %w", prefix, err), http.StatusInternalServerError)
+	}
+
+	return objects, nil
+}
+
+// write puts the file in place through a temporary file, so that a reader
+// never sees half of an object.
+func (r *PhotoRepository) write(objectKey string, file io.Reader) error {
+	name := r.path(objectKey)
+
+	err := os.MkdirAll(filepath.Dir(name), 0o755)
+	if err != nil {
+		return apperr.WithHTTPStatus(fmt.Errorf("can't create directory for object %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return apperr.WithHTTPStatus(fmt.Errorf("can't create object %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, file)
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return apperr.WithHTTPStatus(fmt.Errorf("can't write object %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+
+	err = os.Rename(tmp.Name(), name)
+	if err != nil {
+		return apperr.WithHTTPStatus(fmt.Errorf("can't write object %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (r *PhotoRepository) path(objectKey string) string {
+	return filepath.Join(r.root, filepath.FromSlash(objectKey))
+}
+
+func (r *PhotoRepository) objectURL(objectKey string) string {
+	return r.baseURL + "/" + objectKey
+}
+
+func (r *PhotoRepository) signedURL(method string, objectKey string, expiresAt time.Time, contentType string, size int64) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", r.sign(method, objectKey, expires, contentType, size))
+
+	return r.objectURL(objectKey) + "?" + query.Encode()
+}
+
+func (r *PhotoRepository) sign(method string, objectKey string, expires string, contentType string, size int64) string {
+	mac := hmac.New(sha256.New, r.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%d", method, objectKey, expires, contentType, size)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func stagingKey(userID string, uploadID string) string {
+	return fmt.Sprintf("staging/users/%s/%s", userID, uploadID)
+}
@@ -0,0 +1,62 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+	pgclient "github.com/kurochkinivan/Meet/pkg/pgClient"
+)
+
+type AuditRepository struct {
+	client *pgxpool.Pool
+	qb     sq.StatementBuilderType
+}
+
+func NewAuditRepository(client *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{
+		client: client,
+		qb:     sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *AuditRepository) CreateAuditEntry(ctx context.Context, entry *entity.AuditEntry) error {
+	op := "CreateAuditEntry"
+
+	details := entry.Details
+	if details == nil {
+		details = json.RawMessage("{}")
+	}
+
+	sql, args, err := r.qb.
+		Insert(TableAuditLog).
+		Columns(
+			"actor_id",
+			"action",
+			"target_type",
+			"target_id",
+			"details",
+		).
+		Values(
+			entry.ActorID,
+			entry.Action,
+			entry.TargetType,
+			entry.TargetID,
+			details,
+		).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+	pgclient "github.com/kurochkinivan/Meet/pkg/pgClient"
+)
+
+type BanRepository struct {
+	client *pgxpool.Pool
+	qb     sq.StatementBuilderType
+}
+
+func NewBanRepository(client *pgxpool.Pool) *BanRepository {
+	return &BanRepository{
+		client: client,
+		qb:     sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *BanRepository) Ban(ctx context.Context, ban *entity.Ban) error {
+	op := "Ban"
+
+	sql, args, err := r.qb.
+		Insert(TableBans).
+		Columns(
+			"user_id",
+			"kind",
+			"reason",
+			"banned_by",
+			"expires_at",
+		).
+		Values(
+			ban.UserID,
+			ban.Kind,
+			ban.Reason,
+			ban.BannedBy,
+			ban.ExpiresAt,
+		).
+		Suffix(`ON CONFLICT (user_id) DO UPDATE
+			SET kind = EXCLUDED.kind, reason = EXCLUDED.reason, banned_by = EXCLUDED.banned_by,
+				expires_at = EXCLUDED.expires_at, created_at = CURRENT_TIMESTAMP`).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		if pgclient.IsForeignKeyViolation(err) {
+			return apperr.WithHTTPStatus(apperr.ErrUserNotFound, http.StatusNotFound)
+		}
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (r *BanRepository) Unban(ctx context.Context, userID string) error {
+	op := "Unban"
+
+	sql, args, err := r.qb.
+		Delete(TableBans).
+		Where(sq.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// GetBan returns the ban of the user unless it has already expired.
+func (r *BanRepository) GetBan(ctx context.Context, userID string) (*entity.Ban, error) {
+	op := "GetBan"
+
+	sql, args, err := r.qb.
+		Select(
+			"user_id",
+			"kind",
+			"reason",
+			"banned_by",
+			"expires_at",
+			"created_at",
+		).
+		From(TableBans).
+		Where(sq.And{
+			sq.Eq{"user_id": userID},
+			sq.Or{
+				sq.Eq{"expires_at": nil},
+				sq.Expr("expires_at > CURRENT_TIMESTAMP"),
+			},
+		}).
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	ban := &entity.Ban{}
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(
+		&ban.UserID,
+		&ban.Kind,
+		&ban.Reason,
+		&ban.BannedBy,
+		&ban.ExpiresAt,
+		&ban.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperr.ErrNoRows
+		}
+		return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return ban, nil
+}
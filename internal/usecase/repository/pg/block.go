@@ -0,0 +1,145 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+	pgclient "github.com/kurochkinivan/Meet/pkg/pgClient"
+)
+
+type BlockRepository struct {
+	client *pgxpool.Pool
+	qb     sq.StatementBuilderType
+}
+
+func NewBlockRepository(client *pgxpool.Pool) *BlockRepository {
+	return &BlockRepository{
+		client: client,
+		qb:     sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *BlockRepository) Block(ctx context.Context, blockerID string, blockedID string) error {
+	op := "Block"
+
+	sql, args, err := r.qb.
+		Insert(TableBlocks).
+		Columns(
+			"blocker_id",
+			"blocked_id",
+		).
+		Values(
+			blockerID,
+			blockedID,
+		).
+		Suffix("ON CONFLICT (blocker_id, blocked_id) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		if pgclient.IsForeignKeyViolation(err) {
+			return apperr.WithHTTPStatus(apperr.ErrUserNotFound, http.StatusNotFound)
+		}
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (r *BlockRepository) Unblock(ctx context.Context, blockerID string, blockedID string) error {
+	op := "Unblock"
+
+	sql, args, err := r.qb.
+		Delete(TableBlocks).
+		Where(sq.And{
+			sq.Eq{"blocker_id": blockerID},
+			sq.Eq{"blocked_id": blockedID},
+		}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (r *BlockRepository) GetBlocked(ctx context.Context, blockerID string) ([]*entity.BlockedUser, error) {
+	op := "GetBlocked"
+
+	sql, args, err := r.qb.
+		Select(
+			usersField("id"),
+			usersField("name"),
+			blocksField("created_at"),
+		).
+		From(TableBlocks).
+		Join(fmt.Sprintf("%s ON %s.id = %s.blocked_id", TableUsers, TableUsers, TableBlocks)).
+		Where(sq.Eq{blocksField("blocker_id"): blockerID}).
+		OrderBy(blocksField("created_at") + " DESC").
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	rows, err := pgclient.Conn(ctx, r.client).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrDoQuery(op, err), http.StatusInternalServerError)
+	}
+	defer rows.Close()
+
+	blocked := []*entity.BlockedUser{}
+	for rows.Next() {
+		user := &entity.BlockedUser{}
+		err = rows.Scan(
+			&user.UUID,
+			&user.Name,
+			&user.BlockedAt,
+		)
+		if err != nil {
+			return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+		}
+		blocked = append(blocked, user)
+	}
+
+	return blocked, nil
+}
+
+// IsBlocked reports whether either of the users has blocked the other.
+func (r *BlockRepository) IsBlocked(ctx context.Context, userID string, otherID string) (bool, error) {
+	op := "IsBlocked"
+
+	sql, args, err := r.qb.
+		Select("1").
+		Prefix("SELECT EXISTS (").
+		From(TableBlocks).
+		Where(sq.Or{
+			sq.And{sq.Eq{"blocker_id": userID}, sq.Eq{"blocked_id": otherID}},
+			sq.And{sq.Eq{"blocker_id": otherID}, sq.Eq{"blocked_id": userID}},
+		}).
+		Suffix(")").
+		ToSql()
+	if err != nil {
+		return false, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	var blocked bool
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&blocked)
+	if err != nil {
+		return false, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return blocked, nil
+}
@@ -0,0 +1,143 @@
+package pg
+
+import (
+	"context"
+	"net/http"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+	pgclient "github.com/kurochkinivan/Meet/pkg/pgClient"
+)
+
+type DeviceRepository struct {
+	client *pgxpool.Pool
+	qb     sq.StatementBuilderType
+}
+
+func NewDeviceRepository(client *pgxpool.Pool) *DeviceRepository {
+	return &DeviceRepository{
+		client: client,
+		qb:     sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *DeviceRepository) UpsertDeviceToken(ctx context.Context, userID string, token string, platform entity.Platform) error {
+	op := "UpsertDeviceToken"
+
+	sql, args, err := r.qb.
+		Insert(TableDeviceTokens).
+		Columns(
+			"user_id",
+			"token",
+			"platform",
+		).
+		Values(
+			userID,
+			token,
+			platform,
+		).
+		Suffix("ON CONFLICT (token) DO UPDATE SET user_id = EXCLUDED.user_id, platform = EXCLUDED.platform, updated_at = CURRENT_TIMESTAMP").
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (r *DeviceRepository) GetDeviceTokens(ctx context.Context, userID string) ([]*entity.DeviceToken, error) {
+	op := "GetDeviceTokens"
+
+	sql, args, err := r.qb.
+		Select(
+			"id",
+			"user_id",
+			"token",
+			"platform",
+			"created_at",
+			"updated_at",
+		).
+		From(TableDeviceTokens).
+		Where(sq.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	rows, err := pgclient.Conn(ctx, r.client).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrDoQuery(op, err), http.StatusInternalServerError)
+	}
+	defer rows.Close()
+
+	tokens := []*entity.DeviceToken{}
+	for rows.Next() {
+		token := &entity.DeviceToken{}
+		err = rows.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.Token,
+			&token.Platform,
+			&token.CreatedAt,
+			&token.UpdatedAt,
+		)
+		if err != nil {
+			return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+func (r *DeviceRepository) DeleteDeviceToken(ctx context.Context, userID string, token string) error {
+	op := "DeleteDeviceToken"
+
+	sql, args, err := r.qb.
+		Delete(TableDeviceTokens).
+		Where(sq.And{
+			sq.Eq{"user_id": userID},
+			sq.Eq{"token": token},
+		}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (r *DeviceRepository) DeleteDeviceTokens(ctx context.Context, tokens []string) error {
+	op := "DeleteDeviceTokens"
+
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	sql, args, err := r.qb.
+		Delete(TableDeviceTokens).
+		Where(sq.Eq{"token": tokens}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
@@ -0,0 +1,261 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+	pgclient "github.com/kurochkinivan/Meet/pkg/pgClient"
+)
+
+type NotificationRepository struct {
+	client *pgxpool.Pool
+	qb     sq.StatementBuilderType
+}
+
+func NewNotificationRepository(client *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{
+		client: client,
+		qb:     sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *NotificationRepository) CreateNotification(ctx context.Context, userID string, notificationType entity.NotificationType, payload json.RawMessage) (*entity.Notification, error) {
+	op := "CreateNotification"
+
+	if payload == nil {
+		payload = json.RawMessage("{}")
+	}
+
+	sql, args, err := r.qb.
+		Insert(TableNotifications).
+		Columns(
+			"user_id",
+			"type",
+			"payload",
+		).
+		Values(
+			userID,
+			notificationType,
+			payload,
+		).
+		Suffix("RETURNING id, user_id, type, payload, read_at, created_at").
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	notification := &entity.Notification{}
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(
+		&notification.ID,
+		&notification.UserID,
+		&notification.Type,
+		&notification.Payload,
+		&notification.ReadAt,
+		&notification.CreatedAt,
+	)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return notification, nil
+}
+
+func (r *NotificationRepository) GetNotifications(ctx context.Context, userID string, unreadOnly bool, limit, offset uint64) ([]*entity.Notification, error) {
+	op := "GetNotifications"
+
+	where := sq.And{sq.Eq{"user_id": userID}}
+	if unreadOnly {
+		where = append(where, sq.Eq{"read_at": nil})
+	}
+
+	sql, args, err := r.qb.
+		Select(
+			"id",
+			"user_id",
+			"type",
+			"payload",
+			"read_at",
+			"created_at",
+		).
+		From(TableNotifications).
+		Where(where).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(limit).
+		Offset(offset).
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	rows, err := pgclient.Conn(ctx, r.client).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrDoQuery(op, err), http.StatusInternalServerError)
+	}
+	defer rows.Close()
+
+	notifications := []*entity.Notification{}
+	for rows.Next() {
+		notification := &entity.Notification{}
+		err = rows.Scan(
+			&notification.ID,
+			&notification.UserID,
+			&notification.Type,
+			&notification.Payload,
+			&notification.ReadAt,
+			&notification.CreatedAt,
+		)
+		if err != nil {
+			return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID string, notificationID string) error {
+	op := "MarkRead"
+
+	sql, args, err := r.qb.
+		Update(TableNotifications).
+		Set("read_at", sq.Expr("COALESCE(read_at, CURRENT_TIMESTAMP)")).
+		Where(sq.And{
+			sq.Eq{"user_id": userID},
+			sq.Eq{"id": notificationID},
+		}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	commTag, err := pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	if commTag.RowsAffected() == 0 {
+		return apperr.WithHTTPStatus(apperr.ErrNoRows, http.StatusNotFound)
+	}
+
+	return nil
+}
+
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID string) error {
+	op := "MarkAllRead"
+
+	sql, args, err := r.qb.
+		Update(TableNotifications).
+		Set("read_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.And{
+			sq.Eq{"user_id": userID},
+			sq.Eq{"read_at": nil},
+		}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (r *NotificationRepository) IsMuted(ctx context.Context, userID string, notificationType entity.NotificationType) (bool, error) {
+	op := "IsMuted"
+
+	sql, args, err := r.qb.
+		Select("muted").
+		Prefix("SELECT COALESCE((").
+		From(TableNotificationPreferences).
+		Where(sq.And{
+			sq.Eq{"user_id": userID},
+			sq.Eq{"type": notificationType},
+		}).
+		Suffix("), FALSE)").
+		ToSql()
+	if err != nil {
+		return false, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	var muted bool
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&muted)
+	if err != nil {
+		return false, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return muted, nil
+}
+
+func (r *NotificationRepository) GetPreferences(ctx context.Context, userID string) ([]*entity.NotificationPreference, error) {
+	op := "GetPreferences"
+
+	sql, args, err := r.qb.
+		Select(
+			"type",
+			"muted",
+		).
+		From(TableNotificationPreferences).
+		Where(sq.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	rows, err := pgclient.Conn(ctx, r.client).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrDoQuery(op, err), http.StatusInternalServerError)
+	}
+	defer rows.Close()
+
+	preferences := []*entity.NotificationPreference{}
+	for rows.Next() {
+		preference := &entity.NotificationPreference{}
+		err = rows.Scan(
+			&preference.Type,
+			&preference.Muted,
+		)
+		if err != nil {
+			return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+		}
+		preferences = append(preferences, preference)
+	}
+
+	return preferences, nil
+}
+
+func (r *NotificationRepository) SetPreference(ctx context.Context, userID string, preference *entity.NotificationPreference) error {
+	op := "SetPreference"
+
+	sql, args, err := r.qb.
+		Insert(TableNotificationPreferences).
+		Columns(
+			"user_id",
+			"type",
+			"muted",
+		).
+		Values(
+			userID,
+			preference.Type,
+			preference.Muted,
+		).
+		Suffix("ON CONFLICT (user_id, type) DO UPDATE SET muted = EXCLUDED.muted").
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package pg
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+	pgclient "github.com/kurochkinivan/Meet/pkg/pgClient"
+)
+
+type OutboxRepository struct {
+	client *pgxpool.Pool
+	qb     sq.StatementBuilderType
+}
+
+func NewOutboxRepository(client *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{
+		client: client,
+		qb:     sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+// ClaimEvents takes up to limit due events and hides them from other relays
+// for leaseFor by moving next_attempt_at forward. Rows locked by a concurrent
+// claim are skipped, so several relays never pick the same event.
+func (r *OutboxRepository) ClaimEvents(ctx context.Context, limit uint64, leaseFor time.Duration) ([]*entity.Event, error) {
+	op := "ClaimEvents"
+
+	sql, args, err := r.qb.
+		Update(TableOutbox).
+		Set("next_attempt_at", sq.Expr("CURRENT_TIMESTAMP + make_interval(secs => ?)", leaseFor.Seconds())).
+		Where(sq.Expr(`id IN (
+			SELECT id FROM outbox
+			WHERE published_at IS NULL
+				AND dead_at IS NULL
+				AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)`, limit)).
+		Suffix("RETURNING id, event_type, aggregate_id, payload, attempts, delivered_to, created_at").
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	rows, err := pgclient.Conn(ctx, r.client).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrDoQuery(op, err), http.StatusInternalServerError)
+	}
+	defer rows.Close()
+
+	events := []*entity.Event{}
+	for rows.Next() {
+		event := &entity.Event{}
+		err = rows.Scan(
+			&event.ID,
+			&event.Type,
+			&event.AggregateID,
+			&event.Payload,
+			&event.Attempts,
+			&event.DeliveredTo,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+		}
+		events = append(events, event)
+	}
+	// RETURNING does not keep the subquery order.
+	slices.SortFunc(events, func(a, b *entity.Event) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return events, nil
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, eventID int64) error {
+	op := "MarkPublished"
+
+	sql, args, err := r.qb.
+		Update(TableOutbox).
+		Set("published_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("last_error", nil).
+		Where(sq.Eq{"id": eventID}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// MarkFailed records a failed attempt. Sinks in deliveredTo already accepted
+// the event and are skipped on the next attempt at retryAt. A dead event is
+// never retried and stays in the table for inspection.
+func (r *OutboxRepository) MarkFailed(ctx context.Context, eventID int64, reason string, deliveredTo []string, retryAt time.Time, dead bool) error {
+	op := "MarkFailed"
+
+	query := r.qb.
+		Update(TableOutbox).
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("last_error", reason).
+		Set("delivered_to", deliveredTo).
+		Set("next_attempt_at", retryAt).
+		Where(sq.Eq{"id": eventID})
+	if dead {
+		query = query.Set("dead_at", sq.Expr("CURRENT_TIMESTAMP"))
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) CreateEvent(ctx context.Context, eventType entity.EventType, aggregateID string, payload any) error {
+	op := "CreateEvent"
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return apperr.WithHTTPStatus(fmt.Errorf("%s: failed to marshal event payload: %w", op, err), http.StatusInternalServerError)
+	}
+
+	sql, args, err := r.qb.
+		Insert(TableOutbox).
+		Columns(
+			"event_type",
+			"aggregate_id",
+			"payload",
+		).
+		Values(
+			eventType,
+			aggregateID,
+			data,
+		).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
@@ -25,7 +26,7 @@ func NewPhotoRepository(client *pgxpool.Pool) *PhotoRepository {
 	}
 }
 
-func (r *PhotoRepository) CreatePhoto(ctx context.Context, userID string, url string, objectKey string) error {
+func (r *PhotoRepository) CreatePhoto(ctx context.Context, userID string, url string, objectKey string) (*entity.Photo, error) {
 	op := "CreatePhoto"
 
 	sql, args, err := r.qb.
@@ -40,45 +41,407 @@ func (r *PhotoRepository) CreatePhoto(ctx context.Context, userID string, url st
 			url,
 			objectKey,
 		).
+		Suffix("RETURNING id, user_id, object_key, url, status, created_at").
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	photo := &entity.Photo{}
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(
+		&photo.ID,
+		&photo.UserID,
+		&photo.ObjectKey,
+		&photo.URL,
+		&photo.Status,
+		&photo.CreatedAt,
+	)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return photo, nil
+}
+
+// CreateStagedPhoto records an upload waiting in the staging area for the
+// processing pipeline.
+func (r *PhotoRepository) CreateStagedPhoto(ctx context.Context, userID string, stagingKey string) (*entity.Photo, error) {
+	op := "CreateStagedPhoto"
+
+	sql, args, err := r.qb.
+		Insert(TablePhotos).
+		Columns(
+			"user_id",
+			"url",
+			"object_key",
+			"processing",
+			"position",
+		).
+		Values(
+			userID,
+			"",
+			stagingKey,
+			entity.ProcessingQueued,
+			sq.Expr("(SELECT COALESCE(MAX(position) + 1, 0) FROM photos WHERE user_id = ?)", userID),
+		).
+		Suffix("RETURNING id, user_id, object_key, url, processing, status, position, created_at").
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	photo := &entity.Photo{}
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(
+		&photo.ID,
+		&photo.UserID,
+		&photo.ObjectKey,
+		&photo.URL,
+		&photo.Processing,
+		&photo.Status,
+		&photo.Position,
+		&photo.CreatedAt,
+	)
+	if err != nil {
+		if pgclient.IsUniqueViolation(err) {
+			return nil, apperr.ErrUploadConfirmed
+		}
+		return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return photo, nil
+}
+
+// ClaimStagedPhoto takes the oldest queued photo for processing. Photos whose
+// worker died mid-processing are taken again once staleAfter has passed.
+func (r *PhotoRepository) ClaimStagedPhoto(ctx context.Context, staleAfter time.Duration) (*entity.Photo, error) {
+	op := "ClaimStagedPhoto"
+
+	sql, args, err := r.qb.
+		Update(TablePhotos).
+		Set("processing", entity.ProcessingInProgress).
+		Set("processing_started_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Set("processing_attempts", sq.Expr("processing_attempts + 1")).
+		Where(sq.Expr(`id = (
+			SELECT id FROM photos
+			WHERE processing = ?
+				OR (processing = ? AND processing_started_at < CURRENT_TIMESTAMP - make_interval(secs => ?))
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)`, entity.ProcessingQueued, entity.ProcessingInProgress, staleAfter.Seconds())).
+		Suffix("RETURNING id, user_id, object_key, processing, processing_attempts, created_at").
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	photo := &entity.Photo{}
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(
+		&photo.ID,
+		&photo.UserID,
+		&photo.ObjectKey,
+		&photo.Processing,
+		&photo.ProcessingAttempts,
+		&photo.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperr.ErrNoRows
+		}
+		return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return photo, nil
+}
+
+// CompleteProcessing points the photo at its published object. It returns
+// apperr.ErrNoRows if the photo was deleted while being processed.
+func (r *PhotoRepository) CompleteProcessing(ctx context.Context, photoID int64, url string, objectKey string, blurHash string) error {
+	op := "CompleteProcessing"
+
+	var blurHashValue *string
+	if blurHash != "" {
+		blurHashValue = &blurHash
+	}
+
+	sql, args, err := r.qb.
+		Update(TablePhotos).
+		Set("url", url).
+		Set("object_key", objectKey).
+		Set("blurhash", blurHashValue).
+		Set("processing", entity.ProcessingReady).
+		Set("processing_error", nil).
+		Where(sq.And{
+			sq.Eq{"id": photoID},
+			sq.Eq{"processing": entity.ProcessingInProgress},
+		}).
 		ToSql()
 	if err != nil {
 		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
 	}
 
-	commTag, err := r.client.Exec(ctx, sql, args...)
+	commTag, err := pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
 	if err != nil {
 		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
 	}
 
 	if commTag.RowsAffected() == 0 {
-		return apperr.WithHTTPStatus(pgclient.ErrNoRowsAffected, http.StatusInternalServerError)
+		return apperr.ErrNoRows
 	}
 
 	return nil
 }
 
-func (r *PhotoRepository) GetPhotos(ctx context.Context, userID string) ([]*entity.Photo, error) {
-	op := "GetPhotos"
+// FailProcessing puts the photo back in the queue or marks it as failed.
+func (r *PhotoRepository) FailProcessing(ctx context.Context, photoID int64, state entity.ProcessingState, reason string) error {
+	op := "FailProcessing"
 
 	sql, args, err := r.qb.
-		Select(
-			"id",
-			"user_id",
+		Update(TablePhotos).
+		Set("processing", state).
+		Set("processing_error", reason).
+		Where(sq.Eq{"id": photoID}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (r *PhotoRepository) DeletePhotoVariant(ctx context.Context, photoID int64, width int) error {
+	op := "DeletePhotoVariant"
+
+	sql, args, err := r.qb.
+		Delete(TablePhotoVariants).
+		Where(sq.Eq{
+			"photo_id": photoID,
+			"width":    width,
+		}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (r *PhotoRepository) CreatePhotoVariant(ctx context.Context, photoID int64, variant *entity.PhotoVariant) error {
+	op := "CreatePhotoVariant"
+
+	sql, args, err := r.qb.
+		Insert(TablePhotoVariants).
+		Columns(
+			"photo_id",
+			"width",
+			"height",
 			"object_key",
 			"url",
-			"created_at",
 		).
+		Values(
+			photoID,
+			variant.Width,
+			variant.Height,
+			variant.ObjectKey,
+			variant.URL,
+		).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (r *PhotoRepository) GetPhotos(ctx context.Context, userID string) ([]*entity.Photo, error) {
+	op := "GetPhotos"
+
+	sql, args, err := r.qb.
+		Select(photoColumns...).
 		From(TablePhotos).
 		Where(sq.Eq{"user_id": userID}).
+		OrderBy("position", "id").
 		ToSql()
 	if err != nil {
 		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
 	}
 
-	rows, err := r.client.Query(ctx, sql, args...)
+	return r.queryPhotos(ctx, op, sql, args...)
+}
+
+// GetAllPhotos returns every photo in every processing state, oldest first.
+func (r *PhotoRepository) GetAllPhotos(ctx context.Context) ([]*entity.Photo, error) {
+	op := "GetAllPhotos"
+
+	sql, args, err := r.qb.
+		Select(photoColumns...).
+		From(TablePhotos).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	return r.queryPhotos(ctx, op, sql, args...)
+}
+
+// LockPhotoIDs returns the ids of the user's photos in their current order and
+// locks the rows until the end of the transaction.
+func (r *PhotoRepository) LockPhotoIDs(ctx context.Context, userID string) ([]int64, error) {
+	op := "LockPhotoIDs"
+
+	sql, args, err := r.qb.
+		Select("id").
+		From(TablePhotos).
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("position", "id").
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	rows, err := pgclient.Conn(ctx, r.client).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrDoQuery(op, err), http.StatusInternalServerError)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return ids, nil
+}
+
+// LockPhotoCount locks the user row for the rest of the transaction and counts
+// the photos of the user. Uploads that failed processing are never shown and
+// don't count. The count runs as a separate statement, so it sees the photos
+// committed by whoever held the lock before.
+func (r *PhotoRepository) LockPhotoCount(ctx context.Context, userID string) (int, error) {
+	op := "LockPhotoCount"
+
+	sql, args, err := r.qb.
+		Select("1").
+		From(TableUsers).
+		Where(sq.Eq{"id": userID}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return 0, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	var locked int
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, apperr.ErrNoRows
+		}
+		return 0, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	sql, args, err = r.qb.
+		Select("COUNT(*)").
+		From(TablePhotos).
+		Where(sq.And{
+			sq.Eq{"user_id": userID},
+			sq.NotEq{"processing": entity.ProcessingFailed},
+		}).
+		ToSql()
+	if err != nil {
+		return 0, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	var count int
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&count)
+	if err != nil {
+		return 0, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return count, nil
+}
+
+// SetPhotoPositions numbers the photos in the order of photoIDs.
+func (r *PhotoRepository) SetPhotoPositions(ctx context.Context, userID string, photoIDs []int64) error {
+	op := "SetPhotoPositions"
+
+	sql, args, err := r.qb.
+		Update(TablePhotos).
+		Set("position", sq.Expr("array_position(?::int[], id) - 1", photoIDs)).
+		Where(sq.Eq{
+			"user_id": userID,
+			"id":      photoIDs,
+		}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// GetPhotosByStatus returns the moderation queue, oldest photos first.
+func (r *PhotoRepository) GetPhotosByStatus(ctx context.Context, status entity.PhotoStatus, limit, offset uint64) ([]*entity.Photo, error) {
+	op := "GetPhotosByStatus"
+
+	sql, args, err := r.qb.
+		Select(photoColumns...).
+		From(TablePhotos).
+		Where(sq.Eq{"status": status}).
+		OrderBy("created_at", "id").
+		Limit(limit).
+		Offset(offset).
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	return r.queryPhotos(ctx, op, sql, args...)
+}
+
+var photoColumns = []string{
+	"id",
+	"user_id",
+	"object_key",
+	"url",
+	"COALESCE(blurhash, '')",
+	"created_at",
+	"processing",
+	"COALESCE(processing_error, '')",
+	"status",
+	"COALESCE(rejection_reason, '')",
+	"hidden_until",
+	"duplicate_of",
+	"position",
+	photoVariantsColumn,
+}
+
+func (r *PhotoRepository) queryPhotos(ctx context.Context, op string, sql string, args ...any) ([]*entity.Photo, error) {
+	rows, err := pgclient.Conn(ctx, r.client).Query(ctx, sql, args...)
 	if err != nil {
 		return nil, apperr.WithHTTPStatus(pgclient.ErrDoQuery(op, err), http.StatusInternalServerError)
 	}
+	defer rows.Close()
 
 	photos := []*entity.Photo{}
 	for rows.Next() {
@@ -88,7 +451,16 @@ func (r *PhotoRepository) GetPhotos(ctx context.Context, userID string) ([]*enti
 			&photo.UserID,
 			&photo.ObjectKey,
 			&photo.URL,
+			&photo.BlurHash,
 			&photo.CreatedAt,
+			&photo.Processing,
+			&photo.ProcessingError,
+			&photo.Status,
+			&photo.RejectionReason,
+			&photo.HiddenUntil,
+			&photo.DuplicateOf,
+			&photo.Position,
+			&photo.Variants,
 		)
 		if err != nil {
 			return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
@@ -99,6 +471,175 @@ func (r *PhotoRepository) GetPhotos(ctx context.Context, userID string) ([]*enti
 	return photos, nil
 }
 
+// FindDuplicate looks for the closest photo of another user whose hash is
+// within maxDistance bits and returns the cluster it belongs to.
+//
+// The hamming distance can't use an index, so this scans every hashed photo.
+// That is fine for tens of thousands of photos; beyond that the hashes have to
+// be split into maxDistance+1 indexed bands, at least one of which is equal
+// for any two hashes within maxDistance bits.
+func (r *PhotoRepository) FindDuplicate(ctx context.Context, userID string, hash uint64, maxDistance int) (int64, error) {
+	op := "FindDuplicate"
+
+	distance := sq.Expr("bit_count((phash # ?)::bit(64))", int64(hash))
+
+	sql, args, err := r.qb.
+		Select("COALESCE(duplicate_of, id)").
+		From(TablePhotos).
+		Where(sq.And{
+			sq.NotEq{"phash": nil},
+			sq.NotEq{"user_id": userID},
+			sq.Expr("bit_count((phash # ?)::bit(64)) <= ?", int64(hash), maxDistance),
+		}).
+		OrderByClause(distance).
+		OrderBy("id").
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return 0, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	var clusterID int64
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&clusterID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, apperr.ErrNoRows
+		}
+		return 0, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return clusterID, nil
+}
+
+func (r *PhotoRepository) SetPhotoHash(ctx context.Context, photoID int64, hash uint64, duplicateOf *int64) error {
+	op := "SetPhotoHash"
+
+	sql, args, err := r.qb.
+		Update(TablePhotos).
+		Set("phash", int64(hash)).
+		Set("duplicate_of", duplicateOf).
+		Where(sq.Eq{"id": photoID}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// GetDuplicateClusters returns the clusters of near duplicates, most recent
+// first.
+func (r *PhotoRepository) GetDuplicateClusters(ctx context.Context, limit, offset uint64) ([]*entity.PhotoCluster, error) {
+	op := "GetDuplicateClusters"
+
+	sql, args, err := r.qb.
+		Select("DISTINCT duplicate_of").
+		From(TablePhotos).
+		Where(sq.NotEq{"duplicate_of": nil}).
+		OrderBy("duplicate_of DESC").
+		Limit(limit).
+		Offset(offset).
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	rows, err := pgclient.Conn(ctx, r.client).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrDoQuery(op, err), http.StatusInternalServerError)
+	}
+
+	clusterIDs, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	clusters := make([]*entity.PhotoCluster, 0, len(clusterIDs))
+	if len(clusterIDs) == 0 {
+		return clusters, nil
+	}
+
+	sql, args, err = r.qb.
+		Select(photoColumns...).
+		From(TablePhotos).
+		Where(sq.Or{
+			sq.Eq{"id": clusterIDs},
+			sq.Eq{"duplicate_of": clusterIDs},
+		}).
+		OrderBy("COALESCE(duplicate_of, id) DESC", "id").
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	photos, err := r.queryPhotos(ctx, op, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, photo := range photos {
+		clusterID := photo.ID
+		if photo.DuplicateOf != nil {
+			clusterID = *photo.DuplicateOf
+		}
+
+		if len(clusters) == 0 || clusters[len(clusters)-1].ID != clusterID {
+			clusters = append(clusters, &entity.PhotoCluster{ID: clusterID})
+		}
+		cluster := clusters[len(clusters)-1]
+		cluster.Photos = append(cluster.Photos, photo)
+	}
+
+	return clusters, nil
+}
+
+// SetPhotoStatus records the moderation decision and returns the updated photo.
+func (r *PhotoRepository) SetPhotoStatus(ctx context.Context, photoID string, status entity.PhotoStatus, reason string, reviewerID string) (*entity.Photo, error) {
+	op := "SetPhotoStatus"
+
+	var rejectionReason *string
+	if reason != "" {
+		rejectionReason = &reason
+	}
+
+	sql, args, err := r.qb.
+		Update(TablePhotos).
+		Set("status", status).
+		Set("rejection_reason", rejectionReason).
+		Set("reviewed_by", reviewerID).
+		Set("reviewed_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.Eq{"id": photoID}).
+		Suffix("RETURNING id, user_id, object_key, url, status, created_at, " + photoVariantsColumn).
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	photo := &entity.Photo{RejectionReason: reason}
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(
+		&photo.ID,
+		&photo.UserID,
+		&photo.ObjectKey,
+		&photo.URL,
+		&photo.Status,
+		&photo.CreatedAt,
+		&photo.Variants,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperr.ErrNoRows
+		}
+		return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return photo, nil
+}
+
 func (r *PhotoRepository) DeletePhoto(ctx context.Context, userID string, photoID string) error {
 	op := "DeletePhoto"
 
@@ -113,7 +654,7 @@ func (r *PhotoRepository) DeletePhoto(ctx context.Context, userID string, photoI
 		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
 	}
 
-	commTag, err := r.client.Exec(ctx, sql, args...)
+	commTag, err := pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
 	if err != nil {
 		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
 	}
@@ -134,7 +675,13 @@ func (r *PhotoRepository) GetPhoto(ctx context.Context, photoID string) (*entity
 			"user_id",
 			"object_key",
 			"url",
+			"COALESCE(blurhash, '')",
+			"processing",
+			"COALESCE(processing_error, '')",
+			"status",
+			"COALESCE(rejection_reason, '')",
 			"created_at",
+			photoVariantsColumn,
 		).
 		From(TablePhotos).
 		Where(sq.Eq{"id": photoID}).
@@ -144,12 +691,18 @@ func (r *PhotoRepository) GetPhoto(ctx context.Context, photoID string) (*entity
 	}
 
 	photo := &entity.Photo{}
-	err = r.client.QueryRow(ctx, sql, args...).Scan(
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(
 		&photo.ID,
 		&photo.UserID,
 		&photo.ObjectKey,
 		&photo.URL,
+		&photo.BlurHash,
+		&photo.Processing,
+		&photo.ProcessingError,
+		&photo.Status,
+		&photo.RejectionReason,
 		&photo.CreatedAt,
+		&photo.Variants,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -0,0 +1,415 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+	pgclient "github.com/kurochkinivan/Meet/pkg/pgClient"
+)
+
+type ReportRepository struct {
+	client *pgxpool.Pool
+	qb     sq.StatementBuilderType
+}
+
+func NewReportRepository(client *pgxpool.Pool) *ReportRepository {
+	return &ReportRepository{
+		client: client,
+		qb:     sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *ReportRepository) CreateReport(ctx context.Context, report *entity.Report) (*entity.Report, error) {
+	op := "CreateReport"
+
+	sql, args, err := r.qb.
+		Insert(TableReports).
+		Columns(
+			"reporter_id",
+			"target_type",
+			"target_id",
+			"reason",
+			"comment",
+		).
+		Values(
+			report.ReporterID,
+			report.TargetType,
+			report.TargetID,
+			report.Reason,
+			report.Comment,
+		).
+		Suffix(`ON CONFLICT (reporter_id, target_type, target_id) DO UPDATE
+			SET reason = EXCLUDED.reason, comment = EXCLUDED.comment, updated_at = CURRENT_TIMESTAMP
+			RETURNING id, reporter_id, target_type, target_id, reason, comment, status, created_at, updated_at`).
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	created := &entity.Report{}
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(
+		&created.ID,
+		&created.ReporterID,
+		&created.TargetType,
+		&created.TargetID,
+		&created.Reason,
+		&created.Comment,
+		&created.Status,
+		&created.CreatedAt,
+		&created.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return created, nil
+}
+
+// CreateSystemReport files a report without a reporter, unless the same
+// report is already on file, so redelivered events don't stack up reports.
+func (r *ReportRepository) CreateSystemReport(ctx context.Context, report *entity.Report) error {
+	op := "CreateSystemReport"
+
+	sql, args, err := r.qb.
+		Insert(TableReports).
+		Columns(
+			"target_type",
+			"target_id",
+			"reason",
+			"comment",
+		).
+		Select(sq.
+			Select().
+			Column("?::text", report.TargetType).
+			Column("?::text", report.TargetID).
+			Column("?::text", report.Reason).
+			Column("?::text", report.Comment).
+			Where(`NOT EXISTS (
+				SELECT 1 FROM reports
+				WHERE reporter_id IS NULL AND target_type = ? AND target_id = ? AND reason = ?
+			)`, report.TargetType, report.TargetID, report.Reason),
+		).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// GetReportQueue returns one row per reported target, the oldest report in the
+// given status, together with the number of such reports against the target.
+func (r *ReportRepository) GetReportQueue(ctx context.Context, status entity.ReportStatus, limit, offset uint64) ([]*entity.Report, error) {
+	op := "GetReportQueue"
+
+	inner := r.qb.
+		Select(
+			"DISTINCT ON (target_type, target_id) id",
+			"reporter_id",
+			"target_type",
+			"target_id",
+			"reason",
+			"comment",
+			"status",
+			"COUNT(*) OVER (PARTITION BY target_type, target_id) AS target_count",
+			"created_at",
+			"updated_at",
+		).
+		From(TableReports).
+		Where(sq.Eq{"status": status}).
+		OrderBy("target_type", "target_id", "created_at")
+
+	sql, args, err := r.qb.
+		Select(
+			"id",
+			"reporter_id",
+			"target_type",
+			"target_id",
+			"reason",
+			"comment",
+			"status",
+			"target_count",
+			"created_at",
+			"updated_at",
+		).
+		FromSelect(inner, "queue").
+		OrderBy("created_at", "id").
+		Limit(limit).
+		Offset(offset).
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	return r.queryReports(ctx, op, sql, args...)
+}
+
+func (r *ReportRepository) GetReportsByTarget(ctx context.Context, targetType entity.ReportTargetType, targetID string) ([]*entity.Report, error) {
+	op := "GetReportsByTarget"
+
+	sql, args, err := r.qb.
+		Select(
+			"id",
+			"reporter_id",
+			"target_type",
+			"target_id",
+			"reason",
+			"comment",
+			"status",
+			"COUNT(*) OVER ()",
+			"created_at",
+			"updated_at",
+		).
+		From(TableReports).
+		Where(sq.And{
+			sq.Eq{"target_type": targetType},
+			sq.Eq{"target_id": targetID},
+		}).
+		OrderBy("created_at DESC").
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	return r.queryReports(ctx, op, sql, args...)
+}
+
+func (r *ReportRepository) queryReports(ctx context.Context, op string, sql string, args ...any) ([]*entity.Report, error) {
+	rows, err := pgclient.Conn(ctx, r.client).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrDoQuery(op, err), http.StatusInternalServerError)
+	}
+	defer rows.Close()
+
+	reports := []*entity.Report{}
+	for rows.Next() {
+		report := &entity.Report{}
+		err = rows.Scan(
+			&report.ID,
+			&report.ReporterID,
+			&report.TargetType,
+			&report.TargetID,
+			&report.Reason,
+			&report.Comment,
+			&report.Status,
+			&report.TargetCount,
+			&report.CreatedAt,
+			&report.UpdatedAt,
+		)
+		if err != nil {
+			return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// UpdateReportStatus moves the report and every other unresolved report
+// against the same target to the given status and returns the target.
+func (r *ReportRepository) UpdateReportStatus(ctx context.Context, reportID string, status entity.ReportStatus) (entity.ReportTargetType, string, error) {
+	op := "UpdateReportStatus"
+
+	sql, args, err := r.qb.
+		Update(TableReports).
+		Set("status", status).
+		Set("updated_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.And{
+			sq.Expr("(target_type, target_id) = (SELECT target_type, target_id FROM reports WHERE id = ?)", reportID),
+			sq.Or{
+				sq.Eq{"id": reportID},
+				sq.Eq{"status": []entity.ReportStatus{entity.ReportStatusOpen, entity.ReportStatusReviewing}},
+			},
+		}).
+		Suffix("RETURNING target_type, target_id").
+		ToSql()
+	if err != nil {
+		return "", "", apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	// every updated row has the same target, the first one is enough
+	var targetType entity.ReportTargetType
+	var targetID string
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&targetType, &targetID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", "", apperr.WithHTTPStatus(apperr.ErrNoRows, http.StatusNotFound)
+		}
+		return "", "", apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return targetType, targetID, nil
+}
+
+// CountHidingReporters returns how many distinct users have an unresolved
+// report with a hiding reason against the target. Dismissed reports are not
+// counted, so a moderator's decision is not overturned by the same reporters.
+func (r *ReportRepository) CountHidingReporters(ctx context.Context, targetType entity.ReportTargetType, targetID string) (int, error) {
+	op := "CountHidingReporters"
+
+	sql, args, err := r.qb.
+		Select("COUNT(DISTINCT reporter_id)").
+		From(TableReports).
+		Where(sq.And{
+			sq.Eq{"target_type": targetType},
+			sq.Eq{"target_id": targetID},
+			sq.Eq{"reason": entity.HidingReportReasons},
+			sq.Eq{"status": []entity.ReportStatus{entity.ReportStatusOpen, entity.ReportStatusReviewing}},
+		}).
+		ToSql()
+	if err != nil {
+		return 0, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	var count int
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&count)
+	if err != nil {
+		return 0, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return count, nil
+}
+
+func (r *ReportRepository) HideUser(ctx context.Context, userID string, until time.Time) error {
+	op := "HideUser"
+
+	sql, args, err := r.qb.
+		Update(TableUsers).
+		Set("hidden_until", sq.Expr("GREATEST(hidden_until, ?::timestamptz)", until)).
+		Where(sq.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	commTag, err := pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	if commTag.RowsAffected() == 0 {
+		return apperr.WithHTTPStatus(apperr.ErrReportTargetNotFound, http.StatusNotFound)
+	}
+
+	return nil
+}
+
+// HidePhoto hides the photo and returns the id of its owner.
+func (r *ReportRepository) HidePhoto(ctx context.Context, photoID string, until time.Time) (string, error) {
+	op := "HidePhoto"
+
+	sql, args, err := r.qb.
+		Update(TablePhotos).
+		Set("hidden_until", sq.Expr("GREATEST(hidden_until, ?::timestamptz)", until)).
+		Where(sq.Eq{"id": photoID}).
+		Suffix("RETURNING user_id").
+		ToSql()
+	if err != nil {
+		return "", apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	var userID string
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", apperr.WithHTTPStatus(apperr.ErrReportTargetNotFound, http.StatusNotFound)
+		}
+		return "", apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return userID, nil
+}
+
+// UnhideUser lifts the automatic hiding of the user.
+func (r *ReportRepository) UnhideUser(ctx context.Context, userID string) error {
+	op := "UnhideUser"
+
+	sql, args, err := r.qb.
+		Update(TableUsers).
+		Set("hidden_until", nil).
+		Where(sq.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// UnhidePhoto lifts the automatic hiding of the photo and returns the id of
+// its owner, empty if the photo is gone.
+func (r *ReportRepository) UnhidePhoto(ctx context.Context, photoID string) (string, error) {
+	op := "UnhidePhoto"
+
+	sql, args, err := r.qb.
+		Update(TablePhotos).
+		Set("hidden_until", nil).
+		Where(sq.Eq{"id": photoID}).
+		Suffix("RETURNING user_id").
+		ToSql()
+	if err != nil {
+		return "", apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	var userID string
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return userID, nil
+}
+
+func (r *ReportRepository) TargetExists(ctx context.Context, targetType entity.ReportTargetType, targetID string) (bool, error) {
+	op := "TargetExists"
+
+	var table string
+	switch targetType {
+	case entity.ReportTargetUser:
+		table = TableUsers
+	case entity.ReportTargetPhoto:
+		table = TablePhotos
+	default:
+		// messages are not stored by this service yet
+		return true, nil
+	}
+
+	sql, args, err := r.qb.
+		Select("1").
+		Prefix("SELECT EXISTS (").
+		From(table).
+		Where(sq.Eq{"id": targetID}).
+		Suffix(")").
+		ToSql()
+	if err != nil {
+		return false, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	var exists bool
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&exists)
+	if err != nil {
+		return false, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return exists, nil
+}
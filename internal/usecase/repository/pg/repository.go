@@ -2,16 +2,33 @@ package pg
 
 import (
 	"github.com/jackc/pgx/v5/pgxpool"
+	pgclient "github.com/kurochkinivan/Meet/pkg/pgClient"
 )
 
 type Repositories struct {
-	UserRepository  *UserRepository
-	PhotoRepository *PhotoRepository
+	TxManager              *pgclient.TxManager
+	UserRepository         *UserRepository
+	PhotoRepository        *PhotoRepository
+	NotificationRepository *NotificationRepository
+	DeviceRepository       *DeviceRepository
+	OutboxRepository       *OutboxRepository
+	BlockRepository        *BlockRepository
+	ReportRepository       *ReportRepository
+	BanRepository          *BanRepository
+	AuditRepository        *AuditRepository
 }
 
 func NewRepositories(client *pgxpool.Pool) *Repositories {
 	return &Repositories{
-		UserRepository:  NewUserRepository(client),
-		PhotoRepository: NewPhotoRepository(client),
+		TxManager:              pgclient.NewTxManager(client),
+		UserRepository:         NewUserRepository(client),
+		PhotoRepository:        NewPhotoRepository(client),
+		NotificationRepository: NewNotificationRepository(client),
+		DeviceRepository:       NewDeviceRepository(client),
+		OutboxRepository:       NewOutboxRepository(client),
+		BlockRepository:        NewBlockRepository(client),
+		ReportRepository:       NewReportRepository(client),
+		BanRepository:          NewBanRepository(client),
+		AuditRepository:        NewAuditRepository(client),
 	}
 }
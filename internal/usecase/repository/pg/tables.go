@@ -3,8 +3,18 @@ package pg
 import "fmt"
 
 const (
-	TableUsers  = "users"
-	TablePhotos = "photos"
+	TableUsers         = "users"
+	TablePhotos        = "photos"
+	TablePhotoVariants = "photo_variants"
+
+	TableNotifications           = "notifications"
+	TableNotificationPreferences = "notification_preferences"
+	TableDeviceTokens            = "device_tokens"
+	TableOutbox                  = "outbox"
+	TableBlocks                  = "blocks"
+	TableReports                 = "reports"
+	TableBans                    = "bans"
+	TableAuditLog                = "audit_log"
 )
 
 func usersField(field string) string {
@@ -14,3 +24,14 @@ func usersField(field string) string {
 func photosField(field string) string {
 	return fmt.Sprintf("%s.%s", TablePhotos, field)
 }
+
+func blocksField(field string) string {
+	return fmt.Sprintf("%s.%s", TableBlocks, field)
+}
+
+// photoVariantsColumn aggregates the variants of the photo in the current row
+// into a json array ordered by width.
+var photoVariantsColumn = fmt.Sprintf(`COALESCE((
+	SELECT json_agg(json_build_object('width', v.width, 'height', v.height, 'object_key', v.object_key, 'url', v.url) ORDER BY v.width)
+	FROM %s v WHERE v.photo_id = %s
+), '[]'::json)`, TablePhotoVariants, photosField("id"))
@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/kurochkinivan/Meet/internal/apperr"
@@ -27,6 +29,62 @@ func NewUserRepository(client *pgxpool.Pool) *UserRepository {
 	}
 }
 
+func (r *UserRepository) Create(ctx context.Context, user *entity.User) (*entity.User, error) {
+	op := "Create"
+
+	sql, args, err := r.qb.
+		Insert(TableUsers).
+		Columns(
+			"name",
+			"birthday",
+			"sex",
+			"phone",
+			"password",
+			"location",
+		).
+		Values(
+			user.Name,
+			user.BirthDay,
+			user.Sex,
+			user.Phone,
+			user.Password,
+			sq.Expr("ST_SetSRID(ST_MakePoint(?, ?), 4326)", user.Location.Longitude, user.Location.Latitude),
+		).
+		Suffix(`RETURNING
+			id,
+			name,
+			birthday,
+			sex,
+			phone,
+			ST_X(location::geometry) AS longitude,
+			ST_Y(location::geometry) AS latitude,
+			created_at`).
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	created := &entity.User{}
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(
+		&created.UUID,
+		&created.Name,
+		&created.BirthDay,
+		&created.Sex,
+		&created.Phone,
+		&created.Location.Longitude,
+		&created.Location.Latitude,
+		&created.CreatedAt,
+	)
+	if err != nil {
+		if pgclient.IsUniqueViolation(err) {
+			return nil, apperr.ErrUserExists
+		}
+		return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return created, nil
+}
+
 func (r *UserRepository) CreateIfNotExists(ctx context.Context, user *entity.User) error {
 	op := "CreateIfNotExists"
 
@@ -54,7 +112,7 @@ func (r *UserRepository) CreateIfNotExists(ctx context.Context, user *entity.Use
 		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
 	}
 
-	_, err = r.client.Exec(ctx, sql, args...)
+	_, err = pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
 	if err != nil {
 		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
 	}
@@ -84,7 +142,7 @@ func (r *UserRepository) GetByPhone(ctx context.Context, phone string) (*entity.
 	}
 
 	var user entity.User
-	err = r.client.QueryRow(ctx, sql, args...).Scan(
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(
 		&user.UUID,
 		&user.Name,
 		&user.BirthDay,
@@ -123,7 +181,7 @@ func (r *UserRepository) GetIfExists(ctx context.Context, phone, password string
 	}
 
 	var user entity.User
-	err = r.client.QueryRow(ctx, sql, args...).Scan(
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(
 		&user.UUID,
 		&user.Name,
 		&user.BirthDay,
@@ -153,29 +211,46 @@ func (r *UserRepository) GetByID(ctx context.Context, userID string) (*entity.Us
 			usersField("birthday"),
 			usersField("sex"),
 			usersField("phone"),
+			usersField("role"),
 			"ST_X(users.location::geometry) AS longitude",
 			"ST_Y(users.location::geometry) AS latitude",
 			usersField("created_at"),
+			usersField("hidden_until"),
+			usersField("deletion_scheduled_at"),
 			photosField("id"),
+			photosField("object_key"),
 			photosField("url"),
+			photosField("blurhash"),
+			photoVariantsColumn,
 		).
 		From(TableUsers).
-		LeftJoin(fmt.Sprintf("%s ON %s.id = %s.user_id", TablePhotos, TableUsers, TablePhotos)).
+		LeftJoin(fmt.Sprintf("%s ON %s.id = %s.user_id AND %s = '%s' AND %s = '%s' AND (%s IS NULL OR %[8]s < CURRENT_TIMESTAMP)",
+			TablePhotos, TableUsers, TablePhotos,
+			photosField("processing"), entity.ProcessingReady,
+			photosField("status"), entity.PhotoStatusApproved,
+			photosField("hidden_until"))).
 		Where(sq.Eq{usersField("id"): userID}).
+		OrderBy(photosField("position"), photosField("id")).
 		ToSql()
 	if err != nil {
 		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
 	}
 
-	rows, err := r.client.Query(ctx, query, args...)
+	rows, err := pgclient.Conn(ctx, r.client).Query(ctx, query, args...)
 	if err != nil {
 		return nil, apperr.WithHTTPStatus(pgclient.ErrDoQuery(op, err), http.StatusInternalServerError)
 	}
+	// the query may run in the caller's transaction, which can't be used
+	// again until the rows are closed
+	defer rows.Close()
 
 	user := &entity.User{Photos: make([]*entity.Photo, 0)}
 	for rows.Next() {
 		var photoID sql.NullInt64
+		var photoObjectKey sql.NullString
 		var photoURL sql.NullString
+		var photoBlurHash sql.NullString
+		var variants []*entity.PhotoVariant
 
 		err = rows.Scan(
 			&user.UUID,
@@ -183,11 +258,17 @@ func (r *UserRepository) GetByID(ctx context.Context, userID string) (*entity.Us
 			&user.BirthDay,
 			&user.Sex,
 			&user.Phone,
+			&user.Role,
 			&user.Location.Longitude,
 			&user.Location.Latitude,
 			&user.CreatedAt,
+			&user.HiddenUntil,
+			&user.DeletionScheduledAt,
 			&photoID,
+			&photoObjectKey,
 			&photoURL,
+			&photoBlurHash,
+			&variants,
 		)
 		if err != nil {
 			return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
@@ -195,11 +276,18 @@ func (r *UserRepository) GetByID(ctx context.Context, userID string) (*entity.Us
 
 		if photoID.Valid {
 			user.Photos = append(user.Photos, &entity.Photo{
-				ID:  photoID.Int64,
-				URL: photoURL.String,
+				ID:        photoID.Int64,
+				ObjectKey: photoObjectKey.String,
+				URL:       photoURL.String,
+				BlurHash:  photoBlurHash.String,
+				// the join only picks processed photos
+				Processing: entity.ProcessingReady,
+				Variants:   variants,
 			})
 		}
-
+	}
+	if err = rows.Err(); err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrDoQuery(op, err), http.StatusInternalServerError)
 	}
 
 	return user, nil
@@ -220,10 +308,233 @@ func (r *UserRepository) Exists(ctx context.Context, phone string) (bool, error)
 	}
 
 	var exists bool
-	err = r.client.QueryRow(ctx, sql, args...).Scan(&exists)
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&exists)
 	if err != nil {
 		return false, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
 	}
 
 	return exists, nil
 }
+
+func (r *UserRepository) GetRole(ctx context.Context, userID string) (entity.Role, error) {
+	op := "GetRole"
+
+	sql, args, err := r.qb.
+		Select("role").
+		From(TableUsers).
+		Where(sq.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		return "", apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	var role entity.Role
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", apperr.ErrUserNotFound
+		}
+		return "", apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return role, nil
+}
+
+// SearchUsers looks users up by exact id, phone prefix or a part of the name.
+func (r *UserRepository) SearchUsers(ctx context.Context, query string, limit, offset uint64) ([]*entity.User, error) {
+	op := "SearchUsers"
+
+	var where sq.Sqlizer = sq.Or{
+		sq.Like{"phone": query + "%"},
+		sq.ILike{"name": "%" + query + "%"},
+	}
+	if id, err := uuid.Parse(query); err == nil {
+		where = sq.Eq{"id": id}
+	}
+
+	sql, args, err := r.qb.
+		Select(
+			"id",
+			"name",
+			"birthday",
+			"sex",
+			"phone",
+			"role",
+			"created_at",
+			"hidden_until",
+		).
+		From(TableUsers).
+		Where(where).
+		OrderBy("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	rows, err := pgclient.Conn(ctx, r.client).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrDoQuery(op, err), http.StatusInternalServerError)
+	}
+	defer rows.Close()
+
+	users := []*entity.User{}
+	for rows.Next() {
+		user := &entity.User{}
+		err = rows.Scan(
+			&user.UUID,
+			&user.Name,
+			&user.BirthDay,
+			&user.Sex,
+			&user.Phone,
+			&user.Role,
+			&user.CreatedAt,
+			&user.HiddenUntil,
+		)
+		if err != nil {
+			return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// ScheduleDeletion marks the user for erasure at the given time. A deletion
+// that is scheduled already keeps its time, the returned one is in effect.
+func (r *UserRepository) ScheduleDeletion(ctx context.Context, userID string, at time.Time) (time.Time, error) {
+	op := "ScheduleDeletion"
+
+	sql, args, err := r.qb.
+		Update(TableUsers).
+		Set("deletion_scheduled_at", sq.Expr("COALESCE(deletion_scheduled_at, ?)", at)).
+		Where(sq.Eq{"id": userID}).
+		Suffix("RETURNING deletion_scheduled_at").
+		ToSql()
+	if err != nil {
+		return time.Time{}, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	var scheduledAt time.Time
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&scheduledAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, apperr.WithHTTPStatus(apperr.ErrUserNotFound, http.StatusNotFound)
+		}
+		return time.Time{}, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return scheduledAt, nil
+}
+
+// IsDeletionScheduled reports false for unknown users.
+func (r *UserRepository) IsDeletionScheduled(ctx context.Context, userID string) (bool, error) {
+	op := "IsDeletionScheduled"
+
+	sql, args, err := r.qb.
+		Select("deletion_scheduled_at IS NOT NULL").
+		From(TableUsers).
+		Where(sq.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		return false, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	var scheduled bool
+	err = pgclient.Conn(ctx, r.client).QueryRow(ctx, sql, args...).Scan(&scheduled)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return scheduled, nil
+}
+
+// CancelDeletion returns apperr.ErrNoRows when the user has no deletion
+// scheduled or its grace period is over, the erasure may be running already.
+func (r *UserRepository) CancelDeletion(ctx context.Context, userID string) error {
+	op := "CancelDeletion"
+
+	sql, args, err := r.qb.
+		Update(TableUsers).
+		Set("deletion_scheduled_at", nil).
+		Where(sq.And{
+			sq.Eq{"id": userID},
+			sq.Expr("deletion_scheduled_at > CURRENT_TIMESTAMP"),
+		}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	commTag, err := pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	if commTag.RowsAffected() == 0 {
+		return apperr.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetDueDeletions returns the users whose grace period is over, oldest first.
+func (r *UserRepository) GetDueDeletions(ctx context.Context, limit uint64) ([]string, error) {
+	op := "GetDueDeletions"
+
+	sql, args, err := r.qb.
+		Select("id::text").
+		From(TableUsers).
+		Where(sq.Expr("deletion_scheduled_at <= CURRENT_TIMESTAMP")).
+		OrderBy("deletion_scheduled_at").
+		Limit(limit).
+		ToSql()
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	rows, err := pgclient.Conn(ctx, r.client).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrDoQuery(op, err), http.StatusInternalServerError)
+	}
+
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(pgclient.ErrScan(op, err), http.StatusInternalServerError)
+	}
+
+	return userIDs, nil
+}
+
+// DeleteUser removes the user and, through the cascades, everything that
+// belongs to them. Only a user whose grace period is over is removed, so a
+// deletion canceled in the meantime is honoured; apperr.ErrNoRows otherwise.
+func (r *UserRepository) DeleteUser(ctx context.Context, userID string) error {
+	op := "DeleteUser"
+
+	sql, args, err := r.qb.
+		Delete(TableUsers).
+		Where(sq.And{
+			sq.Eq{"id": userID},
+			sq.Expr("deletion_scheduled_at <= CURRENT_TIMESTAMP"),
+		}).
+		ToSql()
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrCreateQuery(op, err), http.StatusInternalServerError)
+	}
+
+	commTag, err := pgclient.Conn(ctx, r.client).Exec(ctx, sql, args...)
+	if err != nil {
+		return apperr.WithHTTPStatus(pgclient.ErrExec(op, err), http.StatusInternalServerError)
+	}
+
+	if commTag.RowsAffected() == 0 {
+		return apperr.ErrNoRows
+	}
+
+	return nil
+}
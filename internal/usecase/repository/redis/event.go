@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/redis/go-redis/v9"
+)
+
+type EventRepository struct {
+	client *redis.Client
+	stream string
+}
+
+func NewEventRepository(client *redis.Client, stream string) *EventRepository {
+	return &EventRepository{
+		client: client,
+		stream: stream,
+	}
+}
+
+func (r *EventRepository) Name() string {
+	return "redis_stream"
+}
+
+func (r *EventRepository) Publish(ctx context.Context, event *entity.Event) error {
+	err := r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: r.stream,
+		Values: map[string]any{
+			"id":           strconv.FormatInt(event.ID, 10),
+			"type":         string(event.Type),
+			"aggregate_id": event.AggregateID,
+			"payload":      string(event.Payload),
+			"created_at":   event.CreatedAt.Format(time.RFC3339Nano),
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to add event %d to stream %q: %w", event.ID, r.stream, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/redis/go-redis/v9"
+)
+
+// IdempotencyRepository keeps the requests with an idempotency key. A key
+// holds the fingerprint of its request, and the response once there is one.
+type IdempotencyRepository struct {
+	client *redis.Client
+}
+
+func NewIdempotencyRepository(client *redis.Client) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		client: client,
+	}
+}
+
+// Claim takes the key for a new request with the given fingerprint. When the
+// key is taken already, the request it was taken for is returned, or nil if
+// the key expired in the meantime.
+func (r *IdempotencyRepository) Claim(ctx context.Context, key string, fingerprint string, ttl time.Duration) (bool, *entity.IdempotentRequest, error) {
+	data, err := json.Marshal(&entity.IdempotentRequest{Fingerprint: fingerprint})
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to marshal idempotent request, err: %w", err)
+	}
+
+	claimed, err := r.client.SetNX(ctx, getIdempotencyKey(key), data, ttl).Result()
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to claim idempotency key, err: %w", err)
+	}
+	if claimed {
+		return true, nil, nil
+	}
+
+	value, err := r.client.Get(ctx, getIdempotencyKey(key)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil, nil
+		}
+		return false, nil, fmt.Errorf("failed to get idempotent request, err: %w", err)
+	}
+
+	req := &entity.IdempotentRequest{}
+	err = json.Unmarshal(value, req)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to unmarshal idempotent request, err: %w", err)
+	}
+
+	return false, req, nil
+}
+
+func (r *IdempotencyRepository) SaveResponse(ctx context.Context, key string, req *entity.IdempotentRequest, ttl time.Duration) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotent request, err: %w", err)
+	}
+
+	err = r.client.Set(ctx, getIdempotencyKey(key), data, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("failed to save idempotent response, err: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, key string) error {
+	err := r.client.Del(ctx, getIdempotencyKey(key)).Err()
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key, err: %w", err)
+	}
+
+	return nil
+}
+
+func getIdempotencyKey(key string) string {
+	return fmt.Sprintf("idempotency:%s", key)
+}
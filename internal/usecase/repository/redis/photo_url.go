@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PhotoURLRepository caches signed photo urls by object key, so that a photo
+// isn't signed again on every read.
+type PhotoURLRepository struct {
+	client *redis.Client
+}
+
+func NewPhotoURLRepository(client *redis.Client) *PhotoURLRepository {
+	return &PhotoURLRepository{
+		client: client,
+	}
+}
+
+// GetPhotoURLs returns the cached urls, keys without one are left out.
+func (r *PhotoURLRepository) GetPhotoURLs(ctx context.Context, objectKeys []string) (map[string]string, error) {
+	urls := make(map[string]string, len(objectKeys))
+	if len(objectKeys) == 0 {
+		return urls, nil
+	}
+
+	keys := make([]string, 0, len(objectKeys))
+	for _, objectKey := range objectKeys {
+		keys = append(keys, getPhotoURLKey(objectKey))
+	}
+
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get photo urls from cache, err: %w", err)
+	}
+
+	for i, value := range values {
+		if url, ok := value.(string); ok {
+			urls[objectKeys[i]] = url
+		}
+	}
+
+	return urls, nil
+}
+
+func (r *PhotoURLRepository) SetPhotoURL(ctx context.Context, objectKey string, url string, ttl time.Duration) error {
+	err := r.client.Set(ctx, getPhotoURLKey(objectKey), url, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("failed to cache url of photo %q, err: %w", objectKey, err)
+	}
+
+	return nil
+}
+
+func getPhotoURLKey(objectKey string) string {
+	return fmt.Sprintf("photo_url:%s", objectKey)
+}
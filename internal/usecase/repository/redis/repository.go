@@ -8,11 +8,17 @@ import (
 
 type Repositories struct {
 	*UserRepository
+	*EventRepository
+	*PhotoURLRepository
+	*IdempotencyRepository
 }
 
 // TODO: remove hardcode
-func NewRepositories(client *redis.Client, LFUCapacity int64, expiration time.Duration) *Repositories {
+func NewRepositories(client *redis.Client, LFUCapacity int64, expiration time.Duration, eventStream string) *Repositories {
 	return &Repositories{
-		UserRepository: NewUserRepository(client, LFUCapacity, expiration),
+		UserRepository:        NewUserRepository(client, LFUCapacity, expiration),
+		EventRepository:       NewEventRepository(client, eventStream),
+		PhotoURLRepository:    NewPhotoURLRepository(client),
+		IdempotencyRepository: NewIdempotencyRepository(client),
 	}
 }
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -14,29 +16,42 @@ import (
 	"github.com/aws/smithy-go"
 	"github.com/google/uuid"
 	"github.com/kurochkinivan/Meet/internal/apperr"
+	"github.com/kurochkinivan/Meet/internal/entity"
+	"github.com/kurochkinivan/Meet/pkg/imgproc"
 	"github.com/sirupsen/logrus"
 )
 
 type PhotoRepository struct {
-	client     *s3.Client
-	bucketName string
+	client        *s3.Client
+	presignClient *s3.PresignClient
+	bucketName    string
+	urlTemplate   string
+	presignExpiry time.Duration
+	urlExpiry     time.Duration
 }
 
-func NewPhotoRepository(client *s3.Client, bucketName string) *PhotoRepository {
+// NewPhotoRepository builds object urls from urlTemplate, where {bucket} and
+// {key} are replaced with the bucket name and the object key.
+func NewPhotoRepository(client *s3.Client, bucketName string, urlTemplate string, presignExpiry, urlExpiry time.Duration) *PhotoRepository {
 	return &PhotoRepository{
-		client:     client,
-		bucketName: bucketName,
+		client:        client,
+		presignClient: s3.NewPresignClient(client),
+		bucketName:    bucketName,
+		urlTemplate:   urlTemplate,
+		presignExpiry: presignExpiry,
+		urlExpiry:     urlExpiry,
 	}
 }
 
-func (r *PhotoRepository) UploadPhoto(ctx context.Context, userID string, file io.Reader) (url, objectKey string, err error) {
-	objectKey = fmt.Sprintf("users/%s/photos/%s.jpg", userID, uuid.New().String())
+func (r *PhotoRepository) UploadPhoto(ctx context.Context, userID string, file io.Reader, format imgproc.Format) (url, objectKey string, err error) {
+	objectKey = fmt.Sprintf("users/%s/photos/%s%s", userID, uuid.New().String(), format.Extension())
 
 	_, err = r.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(r.bucketName),
-		Key:    aws.String(objectKey),
-		Body:   file,
-		ACL:    types.ObjectCannedACLPublicRead,
+		Bucket:      aws.String(r.bucketName),
+		Key:         aws.String(objectKey),
+		Body:        file,
+		ContentType: aws.String(format.ContentType()),
+		ACL:         types.ObjectCannedACLPrivate,
 	})
 	if err != nil {
 		var apiErr smithy.APIError
@@ -55,10 +70,182 @@ func (r *PhotoRepository) UploadPhoto(ctx context.Context, userID string, file i
 		return "", "", apperr.WithHTTPStatus(fmt.Errorf("failed attempt to wait for object %s to exist", objectKey), http.StatusInternalServerError)
 	}
 
-	url = fmt.Sprintf("https://storage.yandexcloud.net/%s/%s", r.bucketName, objectKey)
+	url = r.objectURL(objectKey)
+	return url, objectKey, nil
+}
+
+// UploadStaging keeps a raw upload under the staging prefix until it has been
+// processed. Staged objects are never signed for reading.
+func (r *PhotoRepository) UploadStaging(ctx context.Context, userID string, file io.Reader) (objectKey string, err error) {
+	objectKey = stagingKey(userID, uuid.New().String())
+
+	_, err = r.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(r.bucketName),
+		Key:    aws.String(objectKey),
+		Body:   file,
+		ACL:    types.ObjectCannedACLPrivate,
+	})
+	if err != nil {
+		return "", apperr.WithHTTPStatus(fmt.Errorf("can't upload file with objectkey %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+
+	return objectKey, nil
+}
+
+// PresignStaging signs a PUT of exactly size bytes of contentType into the
+// staging area of the user. The upload id is the last part of the key.
+func (r *PhotoRepository) PresignStaging(ctx context.Context, userID string, contentType string, size int64) (*entity.PresignedUpload, error) {
+	uploadID := uuid.New().String()
+	objectKey := stagingKey(userID, uploadID)
+
+	req, err := r.presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(r.bucketName),
+		Key:           aws.String(objectKey),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(size),
+		ACL:           types.ObjectCannedACLPrivate,
+	}, s3.WithPresignExpires(r.presignExpiry))
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(fmt.Errorf("can't presign upload of object %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+
+	headers := make(map[string]string, len(req.SignedHeader))
+	for name := range req.SignedHeader {
+		if strings.EqualFold(name, "Host") {
+			continue
+		}
+		headers[name] = req.SignedHeader.Get(name)
+	}
+
+	return &entity.PresignedUpload{
+		ID:        uploadID,
+		URL:       req.URL,
+		Method:    req.Method,
+		Headers:   headers,
+		ExpiresAt: time.Now().Add(r.presignExpiry),
+	}, nil
+}
+
+// HeadStaging checks that a direct upload has arrived and returns its key and
+// size. It returns apperr.ErrNoRows if there is no such object.
+func (r *PhotoRepository) HeadStaging(ctx context.Context, userID string, uploadID string) (objectKey string, size int64, err error) {
+	objectKey = stagingKey(userID, uploadID)
+
+	out, err := r.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(r.bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return "", 0, apperr.ErrNoRows
+		}
+		return "", 0, apperr.WithHTTPStatus(fmt.Errorf("can't head object %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+
+	return objectKey, aws.ToInt64(out.ContentLength), nil
+}
+
+// ListObjects returns every object under the prefix.
+func (r *PhotoRepository) ListObjects(ctx context.Context, prefix string) ([]*entity.StoredObject, error) {
+	objects := make([]*entity.StoredObject, 0)
+
+	paginator := s3.NewListObjectsV2Paginator(r.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(r.bucketName),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, apperr.WithHTTPStatus(fmt.Errorf("can't list objects with prefix %s, err: %w", prefix, err), http.StatusInternalServerError)
+		}
+
+		for _, object := range page.Contents {
+			objects = append(objects, &entity.StoredObject{
+				Key:          aws.ToString(object.Key),
+				LastModified: aws.ToTime(object.LastModified),
+			})
+		}
+	}
+
+	return objects, nil
+}
+
+// MakePrivate drops any grants the object has, objects uploaded before photo
+// urls were signed are still public-read.
+func (r *PhotoRepository) MakePrivate(ctx context.Context, objectKey string) error {
+	_, err := r.client.PutObjectAcl(ctx, &s3.PutObjectAclInput{
+		Bucket: aws.String(r.bucketName),
+		Key:    aws.String(objectKey),
+		ACL:    types.ObjectCannedACLPrivate,
+	})
+	if err != nil {
+		return apperr.WithHTTPStatus(fmt.Errorf("can't make object %s private, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (r *PhotoRepository) objectURL(objectKey string) string {
+	return strings.NewReplacer("{bucket}", r.bucketName, "{key}", objectKey).Replace(r.urlTemplate)
+}
+
+func stagingKey(userID string, uploadID string) string {
+	return fmt.Sprintf("staging/users/%s/%s", userID, uploadID)
+}
+
+func (r *PhotoRepository) DownloadPhoto(ctx context.Context, objectKey string) ([]byte, error) {
+	out, err := r.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(fmt.Errorf("can't get object %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, apperr.WithHTTPStatus(fmt.Errorf("can't read object %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+
+	return data, nil
+}
+
+// UploadVariant stores a resized copy next to the original, the key gets the
+// width as a suffix: users/<id>/photos/<uuid>_480.jpg.
+func (r *PhotoRepository) UploadVariant(ctx context.Context, originalKey string, width int, file io.Reader, format imgproc.Format) (url, objectKey string, err error) {
+	objectKey = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(originalKey, path.Ext(originalKey)), width, format.Extension())
+
+	_, err = r.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(r.bucketName),
+		Key:         aws.String(objectKey),
+		Body:        file,
+		ContentType: aws.String(format.ContentType()),
+		ACL:         types.ObjectCannedACLPrivate,
+	})
+	if err != nil {
+		return "", "", apperr.WithHTTPStatus(fmt.Errorf("can't upload file with objectkey %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+
+	url = r.objectURL(objectKey)
 	return url, objectKey, nil
 }
 
+// SignPhotoURL returns a url the object can be read by until it expires. The
+// bucket is private, this is the only way clients get to the photos.
+func (r *PhotoRepository) SignPhotoURL(ctx context.Context, objectKey string) (string, error) {
+	req, err := r.presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.bucketName),
+		Key:    aws.String(objectKey),
+	}, s3.WithPresignExpires(r.urlExpiry))
+	if err != nil {
+		return "", apperr.WithHTTPStatus(fmt.Errorf("can't sign url of object %s, err: %w", objectKey, err), http.StatusInternalServerError)
+	}
+
+	return req.URL, nil
+}
+
 func (r *PhotoRepository) DeletePhoto(ctx context.Context, objectKey string) error {
 	_, err := r.client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(r.bucketName),
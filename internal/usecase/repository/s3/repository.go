@@ -1,13 +1,17 @@
 package s3
 
-import "github.com/aws/aws-sdk-go-v2/service/s3"
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
 
 type Repositories struct {
 	*PhotoRepository
 }
 
-func NewRepositories(client *s3.Client, bucketName string) *Repositories {
+func NewRepositories(client *s3.Client, bucketName string, urlTemplate string, presignExpiry, urlExpiry time.Duration) *Repositories {
 	return &Repositories{
-		PhotoRepository: NewPhotoRepository(client, bucketName),
+		PhotoRepository: NewPhotoRepository(client, bucketName, urlTemplate, presignExpiry, urlExpiry),
 	}
 }
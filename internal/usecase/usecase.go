@@ -1,20 +1,82 @@
 package usecase
 
 import (
+	"context"
+
 	"github.com/kurochkinivan/Meet/config"
+	"github.com/kurochkinivan/Meet/internal/entity"
 	"github.com/kurochkinivan/Meet/internal/usecase/repository/pg"
 	"github.com/kurochkinivan/Meet/internal/usecase/repository/redis"
-	"github.com/kurochkinivan/Meet/internal/usecase/repository/s3"
+	"github.com/kurochkinivan/Meet/pkg/imgproc"
 )
 
+type TxManager interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// PhotoBlobStorage keeps photo objects and hands out urls to them. It is
+// backed by s3 or by the local disk, depending on the config.
+type PhotoBlobStorage interface {
+	PhotoCloud
+	PhotoURLStorage
+	ObjectLister
+	ObjectACL
+}
+
 type UseCases struct {
 	*PhotoUseCase
+	*PhotoProcessor
+	*Reconciler
+	*AccountEraser
 	*UserUseCase
+	*IdempotencyUseCase
+	*NotificationUseCase
+	*PushUseCase
+	*EventBus
+	*OutboxRelay
+	*BlockUseCase
+	*ReportUseCase
+	*AdminUseCase
 }
 
-func NewUseCases(cfg *config.Config, PGrepositories *pg.Repositories, S3Repositoires *s3.Repositories, redisRepositories *redis.Repositories) *UseCases {
+func NewUseCases(cfg *config.Config, PGrepositories *pg.Repositories, photoBlobs PhotoBlobStorage, redisRepositories *redis.Repositories, pushSender PushSender, eventSinks ...EventSink) *UseCases {
+	pushUseCase := NewPushUseCase(PGrepositories.DeviceRepository, pushSender, cfg.Push.Workers, cfg.Push.QueueSize, cfg.Push.MaxRetries, cfg.Push.RetryInterval)
+	// urls are cached for half of their lifetime, so a client always has at least
+	// the other half to load the photo
+	photoURLSigner := NewPhotoURLSigner(photoBlobs, redisRepositories.PhotoURLRepository, cfg.S3.URLExpiry/2)
+	photoUseCase := NewPhotoUseCase(PGrepositories.PhotoRepository, photoBlobs, redisRepositories.UserRepository, PGrepositories.OutboxRepository, PGrepositories.BlockRepository, PGrepositories.BanRepository, PGrepositories.UserRepository, PGrepositories.TxManager, photoURLSigner, int(cfg.S3.PhotoLimit), imgproc.Limits{
+		MaxFileSize: cfg.Images.MaxFileSize,
+		MaxWidth:    cfg.Images.MaxWidth,
+		MaxHeight:   cfg.Images.MaxHeight,
+	}, cfg.Images.VariantWidths)
+	notificationUseCase := NewNotificationUseCase(PGrepositories.NotificationRepository, pushUseCase)
+	reportUseCase := NewReportUseCase(PGrepositories.ReportRepository, redisRepositories.UserRepository, PGrepositories.TxManager, cfg.Moderation.AutoHideDuration, cfg.Moderation.AutoHideReporters)
+
+	eventBus := NewEventBus()
+	eventBus.Subscribe(entity.EventPhotoDeleted, photoUseCase.HandlePhotoDeleted)
+	eventBus.Subscribe(entity.EventPhotoRejected, notificationUseCase.HandlePhotoRejected)
+	eventBus.Subscribe(entity.EventPhotoDuplicate, reportUseCase.HandlePhotoDuplicate)
+
+	outboxRelay := NewOutboxRelay(PGrepositories.OutboxRepository, cfg.Outbox.PollInterval, cfg.Outbox.BatchSize, OutboxRetry{
+		ClaimTimeout: cfg.Outbox.ClaimTimeout,
+		MaxAttempts:  cfg.Outbox.MaxAttempts,
+		Base:         cfg.Outbox.RetryBase,
+		Max:          cfg.Outbox.RetryMax,
+	}, append([]EventSink{eventBus}, eventSinks...)...)
+
 	return &UseCases{
-		PhotoUseCase: NewPhotoUseCase(PGrepositories.PhotoRepository, S3Repositoires.PhotoRepository, redisRepositories.UserRepository, int(cfg.S3.PhotoLimit)),
-		UserUseCase:  NewUserUseCase(PGrepositories.UserRepository, redisRepositories.UserRepository),
+		PhotoUseCase:        photoUseCase,
+		PhotoProcessor:      NewPhotoProcessor(PGrepositories.PhotoRepository, photoUseCase, cfg.Processing.Workers, cfg.Processing.MaxAttempts, cfg.Processing.PollInterval, cfg.Processing.StaleAfter, cfg.Moderation.DuplicateDistance),
+		Reconciler:          NewReconciler(PGrepositories.PhotoRepository, photoBlobs, photoBlobs, photoUseCase, cfg.Reconcile.Interval, cfg.Reconcile.MinAge, cfg.Reconcile.DryRun),
+		AccountEraser:       NewAccountEraser(PGrepositories.UserRepository, photoBlobs, redisRepositories.UserRepository, cfg.Deletion.PollInterval, cfg.Deletion.BatchSize),
+		UserUseCase:         NewUserUseCase(PGrepositories.UserRepository, redisRepositories.UserRepository, PGrepositories.BlockRepository, PGrepositories.BanRepository, PGrepositories.TxManager, photoURLSigner, cfg.Deletion.GracePeriod),
+		IdempotencyUseCase:  NewIdempotencyUseCase(redisRepositories.IdempotencyRepository, cfg.Idempotency.Window, cfg.Idempotency.LockTTL),
+		NotificationUseCase: notificationUseCase,
+		PushUseCase:         pushUseCase,
+		EventBus:            eventBus,
+		BlockUseCase:        NewBlockUseCase(PGrepositories.BlockRepository),
+		ReportUseCase:       reportUseCase,
+		AdminUseCase:        NewAdminUseCase(PGrepositories.UserRepository, PGrepositories.PhotoRepository, PGrepositories.ReportRepository, PGrepositories.BanRepository, PGrepositories.AuditRepository, PGrepositories.OutboxRepository, redisRepositories.UserRepository, PGrepositories.TxManager, photoUseCase, reportUseCase),
+		OutboxRelay:         outboxRelay,
 	}
 }
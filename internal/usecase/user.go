@@ -3,9 +3,12 @@ package usecase
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/kurochkinivan/Meet/internal/apperr"
 	"github.com/kurochkinivan/Meet/internal/entity"
 	yandexoauth "github.com/kurochkinivan/Meet/internal/external/yandexOAuth"
@@ -15,62 +18,170 @@ import (
 type UserUseCase struct {
 	UserStorage
 	UserCache
+	BlockChecker
+	BanChecker
+	TxManager
+	urls          *PhotoURLSigner
+	deletionGrace time.Duration
 }
 
-func NewUserUseCase(userStorage UserStorage, userCache UserCache) *UserUseCase {
+func NewUserUseCase(userStorage UserStorage, userCache UserCache, blockChecker BlockChecker, banChecker BanChecker, txManager TxManager, urls *PhotoURLSigner, deletionGrace time.Duration) *UserUseCase {
 	return &UserUseCase{
-		UserStorage: userStorage,
-		UserCache:   userCache,
+		UserStorage:   userStorage,
+		UserCache:     userCache,
+		BlockChecker:  blockChecker,
+		BanChecker:    banChecker,
+		TxManager:     txManager,
+		urls:          urls,
+		deletionGrace: deletionGrace,
 	}
 }
 
 type UserStorage interface {
-	Exists(ctx context.Context, phone string) (bool, error)
+	Create(ctx context.Context, user *entity.User) (*entity.User, error)
 	CreateIfNotExists(ctx context.Context, user *entity.User) error
 	GetByID(ctx context.Context, userID string) (*entity.User, error)
 	GetByPhone(ctx context.Context, phone string) (*entity.User, error)
 	GetIfExists(ctx context.Context, phone, password string) (*entity.User, error)
+	ScheduleDeletion(ctx context.Context, userID string, at time.Time) (time.Time, error)
+	CancelDeletion(ctx context.Context, userID string) error
+}
+
+type DeletionChecker interface {
+	IsDeletionScheduled(ctx context.Context, userID string) (bool, error)
 }
 
 type UserCache interface {
 	Get(ctx context.Context, userID string) (*entity.User, bool)
 	Set(ctx context.Context, user *entity.User) error
+	Delete(ctx context.Context, userID string) error
 }
 
-func (u *UserUseCase) GetUserByID(ctx context.Context, userID string) (*entity.User, error) {
-	if user, ok := u.UserCache.Get(ctx, userID); ok {
-		return user, nil
+func (u *UserUseCase) GetUserByID(ctx context.Context, viewerID string, userID string) (*entity.User, error) {
+	err := ensureNotBlocked(ctx, u.BlockChecker, viewerID, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	user, err := u.UserStorage.GetByID(ctx, userID)
+	err = ensureVisible(ctx, u.BanChecker, viewerID, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = u.UserCache.Set(ctx, user); err != nil {
-		logrus.WithError(err).Errorf("failed to set user for user %q", userID)
+	user, ok := u.UserCache.Get(ctx, userID)
+	if !ok {
+		user, err = u.UserStorage.GetByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = u.UserCache.Set(ctx, user); err != nil {
+			logrus.WithError(err).Errorf("failed to set user for user %q", userID)
+		}
+	}
+
+	if viewerID != userID && user.Hidden() {
+		return nil, apperr.WithHTTPStatus(apperr.ErrUserNotFound, http.StatusNotFound)
+	}
+
+	err = u.urls.SignPhotos(ctx, user.Photos...)
+	if err != nil {
+		return nil, err
 	}
 
 	return user, nil
 }
 
-func (u *UserUseCase) Register(ctx context.Context, user *entity.User) (*entity.User, error) {
-	user.Password = u.hashPassword(user.Password)
-	exists, err := u.UserStorage.Exists(ctx, user.Phone)
+// DeleteAccount schedules the erasure of the account once the grace period is
+// over, until then the account is hidden from others and the deletion can be
+// canceled. Deleting again keeps the time scheduled first.
+//
+// The caller proves to own the account with the password or an OAuth token,
+// the X-User-ID header can't be trusted with an irreversible action.
+func (u *UserUseCase) DeleteAccount(ctx context.Context, userID string, password string, oauthToken string) (time.Time, error) {
+	err := u.verifyOwner(ctx, userID, password, oauthToken)
 	if err != nil {
-		return nil, err
+		return time.Time{}, err
 	}
 
-	if exists {
-		return nil, apperr.ErrUserExists
+	scheduledAt, err := u.UserStorage.ScheduleDeletion(ctx, userID, time.Now().Add(u.deletionGrace))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to schedule account deletion, err: %w", err)
 	}
 
-	err = u.UserStorage.CreateIfNotExists(ctx, user)
+	err = u.UserCache.Delete(ctx, userID)
 	if err != nil {
-		return nil, err
+		return time.Time{}, fmt.Errorf("failed to evict user from cache, err: %w", err)
+	}
+
+	return scheduledAt, nil
+}
+
+// CancelAccountDeletion keeps the account, it can be canceled only during the
+// grace period and with the same proof as the deletion.
+func (u *UserUseCase) CancelAccountDeletion(ctx context.Context, userID string, password string, oauthToken string) error {
+	err := u.verifyOwner(ctx, userID, password, oauthToken)
+	if err != nil {
+		return err
+	}
+
+	err = u.UserStorage.CancelDeletion(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNoRows) {
+			return apperr.WithHTTPStatus(apperr.ErrDeletionNotScheduled, http.StatusConflict)
+		}
+		return fmt.Errorf("failed to cancel account deletion, err: %w", err)
+	}
+
+	err = u.UserCache.Delete(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to evict user from cache, err: %w", err)
+	}
+
+	return nil
+}
+
+// verifyOwner logs the caller in with either credential and checks that it is
+// the given user. Any failure is reported the same way, so that the response
+// doesn't tell whether the account exists.
+func (u *UserUseCase) verifyOwner(ctx context.Context, userID string, password string, oauthToken string) error {
+	unauthenticated := apperr.WithHTTPStatus(apperr.ErrUnauthenticated, http.StatusUnauthorized)
+
+	if (password == "") == (oauthToken == "") {
+		return apperr.WithHTTPStatus(errors.New("either password or oauth token must be provided"), http.StatusBadRequest)
+	}
+
+	user, err := u.UserStorage.GetByID(ctx, userID)
+	if err != nil || user.UUID == uuid.Nil {
+		return unauthenticated
+	}
+
+	var phone string
+	if oauthToken != "" {
+		yandexResponse, err := yandexoauth.ParseOAuthToken(ctx, oauthToken)
+		if err != nil {
+			return unauthenticated
+		}
+		phone = yandexResponse.Phone.Number
+	} else {
+		owner, err := u.UserStorage.GetIfExists(ctx, user.Phone, u.hashPassword(password))
+		if err != nil {
+			return unauthenticated
+		}
+		phone = owner.Phone
+	}
+
+	if phone != user.Phone {
+		return unauthenticated
 	}
 
-	user, err = u.UserStorage.GetByPhone(ctx, user.Phone)
+	return nil
+}
+
+func (u *UserUseCase) Register(ctx context.Context, user *entity.User) (*entity.User, error) {
+	user.Password = u.hashPassword(user.Password)
+
+	user, err := u.UserStorage.Create(ctx, user)
 	if err != nil {
 		return nil, err
 	}
@@ -84,6 +195,11 @@ func (u *UserUseCase) AuthenticatePhone(ctx context.Context, phone, password str
 		return nil, err
 	}
 
+	err = ensureNotBanned(ctx, u.BanChecker, user.UUID.String())
+	if err != nil {
+		return nil, err
+	}
+
 	return user, nil
 }
 
@@ -105,12 +221,19 @@ func (u *UserUseCase) AuthenticateOAuth(ctx context.Context, OAuth string) (*ent
 		Phone:    yandexResponse.Phone.Number,
 	}
 
-	err = u.UserStorage.CreateIfNotExists(ctx, user)
-	if err != nil {
-		return nil, err
-	}
+	err = u.TxManager.Do(ctx, func(ctx context.Context) error {
+		err := u.UserStorage.CreateIfNotExists(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		user, err = u.UserStorage.GetByPhone(ctx, user.Phone)
+		if err != nil {
+			return err
+		}
 
-	user, err = u.UserStorage.GetByPhone(ctx, user.Phone)
+		return ensureNotBanned(ctx, u.BanChecker, user.UUID.String())
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -123,3 +246,22 @@ func (u *UserUseCase) hashPassword(password string) string {
 	h.Write([]byte(password))
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
+
+// ensureNotDeleted hides accounts that wait for their erasure from everyone
+// but the owner.
+func ensureNotDeleted(ctx context.Context, checker DeletionChecker, viewerID string, userID string) error {
+	if viewerID == userID {
+		return nil
+	}
+
+	scheduled, err := checker.IsDeletionScheduled(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to check account deletion, err: %w", err)
+	}
+
+	if scheduled {
+		return apperr.WithHTTPStatus(apperr.ErrUserNotFound, http.StatusNotFound)
+	}
+
+	return nil
+}
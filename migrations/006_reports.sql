@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS reports (
+    id BIGINT GENERATED ALWAYS AS IDENTITY,
+    reporter_id UUID,
+    target_type TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_reporter_id FOREIGN KEY (reporter_id) REFERENCES users (id)
+        ON UPDATE CASCADE ON DELETE SET NULL,
+    CONSTRAINT target_type_check CHECK (target_type IN ('user', 'photo', 'message')),
+    CONSTRAINT reason_check CHECK (reason IN ('spam', 'fake_profile', 'inappropriate_content', 'harassment', 'underage', 'other')),
+    CONSTRAINT status_check CHECK (status IN ('open', 'reviewing', 'actioned', 'dismissed'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_reporter_target ON reports (reporter_id, target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_reports_target ON reports (target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports (status, created_at);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS hidden_until TIMESTAMPTZ;
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS hidden_until TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+ALTER TABLE bans ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'ban';
+ALTER TABLE bans ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE bans ADD CONSTRAINT kind_check CHECK (kind IN ('ban', 'shadowban'));
@@ -0,0 +1,12 @@
+-- photos uploaded before moderation existed stay published
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'approved';
+ALTER TABLE photos ALTER COLUMN status SET DEFAULT 'pending';
+ALTER TABLE photos ADD CONSTRAINT status_check CHECK (status IN ('pending', 'approved', 'rejected'));
+
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS reviewed_by UUID;
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMPTZ;
+ALTER TABLE photos ADD CONSTRAINT fk_reviewed_by FOREIGN KEY (reviewed_by) REFERENCES users (id)
+    ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_photos_status ON photos (status, created_at);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS photo_variants (
+    photo_id INT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    object_key TEXT NOT NULL,
+    url TEXT NOT NULL,
+    PRIMARY KEY (photo_id, width),
+    CONSTRAINT fk_photo_id FOREIGN KEY (photo_id) REFERENCES photos (id)
+        ON UPDATE CASCADE ON DELETE CASCADE
+);
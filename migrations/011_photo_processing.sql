@@ -0,0 +1,8 @@
+-- photos uploaded before the pipeline existed are already processed
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS processing TEXT NOT NULL DEFAULT 'ready';
+ALTER TABLE photos ADD CONSTRAINT processing_check CHECK (processing IN ('queued', 'processing', 'ready', 'failed'));
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS processing_error TEXT;
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS processing_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS processing_started_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_photos_processing ON photos (id) WHERE processing IN ('queued', 'processing');
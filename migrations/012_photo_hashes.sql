@@ -0,0 +1,9 @@
+-- dHash of the processed photo, compared by hamming distance
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS phash BIGINT;
+-- the earliest photo of another account this one is a near duplicate of,
+-- photos sharing it form a cluster
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS duplicate_of INT;
+ALTER TABLE photos ADD CONSTRAINT fk_duplicate_of FOREIGN KEY (duplicate_of) REFERENCES photos (id)
+    ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_photos_duplicate_of ON photos (duplicate_of) WHERE duplicate_of IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS blurhash TEXT;
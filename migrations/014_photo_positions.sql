@@ -0,0 +1,11 @@
+-- photos are shown in ascending position, the first one is the primary photo
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0;
+
+UPDATE photos SET position = ordered.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at, id) - 1 AS position
+    FROM photos
+) AS ordered
+WHERE photos.id = ordered.id;
+
+CREATE INDEX IF NOT EXISTS idx_photos_user_position ON photos (user_id, position, id);
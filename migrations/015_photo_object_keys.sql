@@ -0,0 +1,2 @@
+-- a direct upload is confirmed by its staging key, it must not become two photos
+CREATE UNIQUE INDEX IF NOT EXISTS idx_photos_object_key ON photos (object_key);
@@ -0,0 +1,6 @@
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL;
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS delivered_to TEXT[] DEFAULT '{}' NOT NULL;
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS dead_at TIMESTAMPTZ;
+
+DROP INDEX IF EXISTS idx_outbox_unpublished;
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (next_attempt_at, id) WHERE published_at IS NULL AND dead_at IS NULL;
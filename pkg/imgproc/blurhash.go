@@ -0,0 +1,122 @@
+package imgproc
+
+import (
+	"image"
+	"math"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	blurHashComponentsX = 4
+	blurHashComponentsY = 3
+	// the placeholder is blurry anyway, there's no point in looking at
+	// more pixels than this
+	blurHashSampleSize = 64
+)
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// BlurHash encodes a compact placeholder of the image, see
+// https://github.com/woltapp/blurhash for the format.
+func BlurHash(data []byte, format Format) (string, error) {
+	src, err := Decode(data, format)
+	if err != nil {
+		return "", err
+	}
+
+	bounds := src.Bounds()
+	width := min(bounds.Dx(), blurHashSampleSize)
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.ApproxBiLinear.Scale(img, img.Bounds(), src, bounds, draw.Src, nil)
+
+	linear := make([][3]float64, width*height)
+	for y := range height {
+		for x := range width {
+			offset := img.PixOffset(x, y)
+			linear[y*width+x] = [3]float64{
+				srgbToLinear(img.Pix[offset]),
+				srgbToLinear(img.Pix[offset+1]),
+				srgbToLinear(img.Pix[offset+2]),
+			}
+		}
+	}
+
+	factors := make([][3]float64, 0, blurHashComponentsX*blurHashComponentsY)
+	for j := range blurHashComponentsY {
+		for i := range blurHashComponentsX {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+
+			var factor [3]float64
+			for y := range height {
+				for x := range width {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(width)) *
+						math.Cos(math.Pi*float64(j)*float64(y)/float64(height))
+					pixel := linear[y*width+x]
+					factor[0] += basis * pixel[0]
+					factor[1] += basis * pixel[1]
+					factor[2] += basis * pixel[2]
+				}
+			}
+
+			scale := normalisation / float64(width*height)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	var hash strings.Builder
+	encodeBase83(&hash, (blurHashComponentsX-1)+(blurHashComponentsY-1)*9, 1)
+
+	dc, ac := factors[0], factors[1:]
+
+	var actualMax float64
+	for _, factor := range ac {
+		actualMax = max(actualMax, math.Abs(factor[0]), math.Abs(factor[1]), math.Abs(factor[2]))
+	}
+	quantisedMax := int(max(0, min(82, math.Floor(actualMax*166-0.5))))
+	maxValue := float64(quantisedMax+1) / 166
+	encodeBase83(&hash, quantisedMax, 1)
+
+	encodeBase83(&hash, linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4)
+
+	for _, factor := range ac {
+		quantise := func(v float64) int {
+			return int(max(0, min(18, math.Floor(signPow(v/maxValue, 0.5)*9+9.5))))
+		}
+		encodeBase83(&hash, quantise(factor[0])*19*19+quantise(factor[1])*19+quantise(factor[2]), 2)
+	}
+
+	return hash.String(), nil
+}
+
+func encodeBase83(b *strings.Builder, value int, length int) {
+	for i := 1; i <= length; i++ {
+		digit := (value / int(math.Pow(83, float64(length-i)))) % 83
+		b.WriteByte(base83Chars[digit])
+	}
+}
+
+func srgbToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := max(0, min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
@@ -0,0 +1,54 @@
+package imgproc
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const tagOrientation = 0x0112
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// exifOrientation reads the orientation tag from IFD0 of an EXIF block. It
+// returns 1 (no transformation) when the tag is missing or malformed.
+func exifOrientation(exif []byte) int {
+	exif = bytes.TrimPrefix(exif, exifHeader)
+	if len(exif) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(exif[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(exif[4:8]))
+	if offset < 8 || offset+2 > len(exif) {
+		return 1
+	}
+
+	count := int(order.Uint16(exif[offset : offset+2]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(exif) {
+			return 1
+		}
+
+		if order.Uint16(exif[entry:entry+2]) != tagOrientation {
+			continue
+		}
+
+		orientation := int(order.Uint16(exif[entry+8 : entry+10]))
+		if orientation < 1 || orientation > 8 {
+			return 1
+		}
+		return orientation
+	}
+
+	return 1
+}
@@ -0,0 +1,87 @@
+package imgproc
+
+import (
+	"bytes"
+	"errors"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrFileTooLarge      = errors.New("image file is too large")
+	ErrDimensions        = errors.New("image dimensions are out of bounds")
+	ErrCorrupted         = errors.New("image is corrupted")
+)
+
+type Format string
+
+const (
+	JPEG Format = "jpeg"
+	PNG  Format = "png"
+	WebP Format = "webp"
+	HEIC Format = "heic"
+)
+
+func (f Format) ContentType() string {
+	switch f {
+	case JPEG:
+		return "image/jpeg"
+	case PNG:
+		return "image/png"
+	case WebP:
+		return "image/webp"
+	case HEIC:
+		return "image/heic"
+	}
+	return "application/octet-stream"
+}
+
+func (f Format) Extension() string {
+	switch f {
+	case JPEG:
+		return ".jpg"
+	case PNG:
+		return ".png"
+	case WebP:
+		return ".webp"
+	case HEIC:
+		return ".heic"
+	}
+	return ""
+}
+
+// ParseContentType maps a declared Content-Type to the format. It's only used
+// to sign direct uploads, the content is still sniffed once it's uploaded.
+func ParseContentType(contentType string) (Format, error) {
+	for _, format := range []Format{JPEG, PNG, WebP, HEIC} {
+		if format.ContentType() == contentType {
+			return format, nil
+		}
+	}
+	return "", ErrUnsupportedFormat
+}
+
+var heicBrands = [][]byte{
+	[]byte("heic"), []byte("heix"), []byte("heim"), []byte("heis"),
+	[]byte("hevc"), []byte("hevx"), []byte("mif1"), []byte("msf1"),
+}
+
+// Detect sniffs the format from the leading bytes of the file. The declared
+// Content-Type and file name are never trusted.
+func Detect(data []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return JPEG, nil
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return PNG, nil
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return WebP, nil
+	case len(data) >= 12 && bytes.Equal(data[4:8], []byte("ftyp")):
+		for _, brand := range heicBrands {
+			if bytes.Equal(data[8:12], brand) {
+				return HEIC, nil
+			}
+		}
+	}
+
+	return "", ErrUnsupportedFormat
+}
@@ -0,0 +1,32 @@
+package imgproc
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// DHash computes the difference hash of the image: it is scaled down to 9x8
+// grayscale and every bit tells whether a pixel is brighter than its right
+// neighbour. Re-encoding, resizing and small edits change only a few bits.
+func DHash(data []byte, format Format) (uint64, error) {
+	src, err := Decode(data, format)
+	if err != nil {
+		return 0, err
+	}
+
+	dst := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := range 8 {
+		for x := range 8 {
+			hash <<= 1
+			if dst.GrayAt(x, y).Y > dst.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash, nil
+}
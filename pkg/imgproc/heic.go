@@ -0,0 +1,294 @@
+package imgproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// heicDimensions reads the image spatial extents ('ispe') properties of a HEIF
+// container. Grid images carry an extent per tile as well, so the largest one
+// is taken as the size of the picture.
+func heicDimensions(data []byte) (int, int, error) {
+	meta, ok := findBox(data, "meta")
+	if !ok || len(meta) < 4 {
+		return 0, 0, fmt.Errorf("%w: heic without meta box", ErrCorrupted)
+	}
+
+	// meta is a full box: skip version and flags
+	iprp, ok := findBox(meta[4:], "iprp")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: heic without item properties", ErrCorrupted)
+	}
+
+	ipco, ok := findBox(iprp, "ipco")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: heic without item properties", ErrCorrupted)
+	}
+
+	var width, height int
+	for _, ispe := range findBoxes(ipco, "ispe") {
+		if len(ispe) < 12 {
+			continue
+		}
+
+		w := int(binary.BigEndian.Uint32(ispe[4:8]))
+		h := int(binary.BigEndian.Uint32(ispe[8:12]))
+		if w*h > width*height {
+			width, height = w, h
+		}
+	}
+
+	if width == 0 || height == 0 {
+		return 0, 0, fmt.Errorf("%w: heic without image extents", ErrCorrupted)
+	}
+
+	return width, height, nil
+}
+
+func findBox(data []byte, boxType string) ([]byte, bool) {
+	boxes := findBoxes(data, boxType)
+	if len(boxes) == 0 {
+		return nil, false
+	}
+	return boxes[0], true
+}
+
+// findBoxes returns the payloads of the top level ISO BMFF boxes of the given
+// type.
+func findBoxes(data []byte, boxType string) [][]byte {
+	var boxes [][]byte
+
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[:4]))
+		header := uint64(8)
+
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return boxes
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			header = 16
+		}
+
+		if size < header || size > uint64(len(data)) {
+			return boxes
+		}
+
+		if string(data[4:8]) == boxType {
+			boxes = append(boxes, data[header:size])
+		}
+		data = data[size:]
+	}
+
+	return boxes
+}
+
+// sanitizeHEIC blanks the Exif and XMP items in place. Item locations stay
+// the same, so the rest of the container is untouched. Orientation of HEIF
+// images is described by the irot and imir properties, which viewers apply,
+// so there is nothing to normalize.
+func sanitizeHEIC(data []byte) ([]byte, Format, error) {
+	meta, ok := findBox(data, "meta")
+	if !ok || len(meta) < 4 {
+		return nil, "", fmt.Errorf("%w: heic without meta box", ErrCorrupted)
+	}
+	meta = meta[4:]
+
+	iinf, ok := findBox(meta, "iinf")
+	if !ok {
+		return data, HEIC, nil
+	}
+
+	metadataItems := heicMetadataItems(iinf)
+	if len(metadataItems) == 0 {
+		return data, HEIC, nil
+	}
+
+	iloc, ok := findBox(meta, "iloc")
+	if !ok {
+		return nil, "", fmt.Errorf("%w: heic without item locations", ErrCorrupted)
+	}
+
+	extents, err := heicItemExtents(iloc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	out := bytes.Clone(data)
+	for id := range metadataItems {
+		for _, extent := range extents[id] {
+			// offset+length could wrap around, so the length is checked against what's left
+			if extent.length == 0 || extent.offset > uint64(len(out)) || extent.length > uint64(len(out))-extent.offset {
+				return nil, "", fmt.Errorf("%w: heic item %d is out of bounds", ErrCorrupted, id)
+			}
+			clear(out[extent.offset : extent.offset+extent.length])
+		}
+	}
+
+	return out, HEIC, nil
+}
+
+// heicMetadataItems returns ids of the Exif and XMP items listed in iinf.
+func heicMetadataItems(iinf []byte) map[uint32]bool {
+	if len(iinf) < 4 {
+		return nil
+	}
+
+	header := 4 + 2
+	if iinf[0] != 0 {
+		header = 4 + 4
+	}
+	if len(iinf) < header {
+		return nil
+	}
+
+	items := make(map[uint32]bool)
+	for _, infe := range findBoxes(iinf[header:], "infe") {
+		if len(infe) < 4 || infe[0] < 2 {
+			continue
+		}
+
+		var id uint32
+		rest := infe[4:]
+		if infe[0] == 2 {
+			if len(rest) < 2 {
+				continue
+			}
+			id, rest = uint32(binary.BigEndian.Uint16(rest)), rest[2:]
+		} else {
+			if len(rest) < 4 {
+				continue
+			}
+			id, rest = binary.BigEndian.Uint32(rest), rest[4:]
+		}
+
+		// protection index, then the item type
+		if len(rest) < 6 {
+			continue
+		}
+		itemType, rest := string(rest[2:6]), rest[6:]
+
+		switch itemType {
+		case "Exif":
+			items[id] = true
+		case "mime":
+			// item name, then the content type, both null terminated
+			if _, after, ok := bytes.Cut(rest, []byte{0}); ok {
+				contentType, _, _ := bytes.Cut(after, []byte{0})
+				if string(contentType) == "application/rdf+xml" {
+					items[id] = true
+				}
+			}
+		}
+	}
+
+	return items
+}
+
+type heicExtent struct {
+	offset uint64
+	length uint64
+}
+
+// heicItemExtents maps item ids to their file extents. Items stored in the
+// idat box or built from other items are skipped: they can't hold metadata
+// written by cameras.
+func heicItemExtents(iloc []byte) (map[uint32][]heicExtent, error) {
+	r := &boxReader{data: iloc}
+
+	version := r.uint(1)
+	r.skip(3)
+	sizes := r.uint(1)
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0x0f)
+	sizes = r.uint(1)
+	baseOffsetSize, indexSize := int(sizes>>4), int(sizes&0x0f)
+	if version == 0 {
+		indexSize = 0
+	}
+
+	var count uint64
+	if version < 2 {
+		count = r.uint(2)
+	} else {
+		count = r.uint(4)
+	}
+
+	extents := make(map[uint32][]heicExtent)
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		var id uint32
+		if version < 2 {
+			id = uint32(r.uint(2))
+		} else {
+			id = uint32(r.uint(4))
+		}
+
+		constructionMethod := uint64(0)
+		if version > 0 {
+			constructionMethod = r.uint(2) & 0x0f
+		}
+		r.skip(2)
+		baseOffset := r.uint(baseOffsetSize)
+
+		extentCount := r.uint(2)
+		for j := uint64(0); j < extentCount && r.err == nil; j++ {
+			r.skip(indexSize)
+			offset := r.uint(offsetSize)
+			length := r.uint(lengthSize)
+
+			if constructionMethod == 0 {
+				if offset > math.MaxUint64-baseOffset {
+					return nil, fmt.Errorf("%w: heic item %d is out of bounds", ErrCorrupted, id)
+				}
+				extents[id] = append(extents[id], heicExtent{
+					offset: baseOffset + offset,
+					length: length,
+				})
+			}
+		}
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return extents, nil
+}
+
+type boxReader struct {
+	data []byte
+	err  error
+}
+
+func (r *boxReader) uint(size int) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	if size > len(r.data) {
+		r.err = fmt.Errorf("%w: truncated heic box", ErrCorrupted)
+		return 0
+	}
+
+	var v uint64
+	for _, b := range r.data[:size] {
+		v = v<<8 | uint64(b)
+	}
+	r.data = r.data[size:]
+
+	return v
+}
+
+func (r *boxReader) skip(size int) {
+	if r.err != nil {
+		return
+	}
+	if size > len(r.data) {
+		r.err = fmt.Errorf("%w: truncated heic box", ErrCorrupted)
+		return
+	}
+	r.data = r.data[size:]
+}
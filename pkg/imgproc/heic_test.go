@@ -0,0 +1,171 @@
+package imgproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func isoBox(boxType string, payload ...[]byte) []byte {
+	box := binary.BigEndian.AppendUint32(nil, 0)
+	box = append(box, boxType...)
+	for _, p := range payload {
+		box = append(box, p...)
+	}
+	binary.BigEndian.PutUint32(box, uint32(len(box)))
+	return box
+}
+
+// fullBox prefixes the payload with version and flags.
+func fullBox(boxType string, version byte, payload ...[]byte) []byte {
+	return isoBox(boxType, append([][]byte{{version, 0, 0, 0}}, payload...)...)
+}
+
+func heicInfe(id uint16, itemType string) []byte {
+	payload := binary.BigEndian.AppendUint16(nil, id)
+	payload = append(payload, 0, 0)
+	payload = append(payload, itemType...)
+	payload = append(payload, 0)
+	return fullBox("infe", 2, payload)
+}
+
+type testExtent struct {
+	id     uint16
+	offset uint64
+	length uint64
+}
+
+// heicIloc writes a version 1 iloc with 8 byte offsets, lengths and base offsets.
+func heicIloc(baseOffset uint64, extents ...testExtent) []byte {
+	payload := []byte{0x88, 0x80}
+	payload = binary.BigEndian.AppendUint16(payload, uint16(len(extents)))
+	for _, extent := range extents {
+		payload = binary.BigEndian.AppendUint16(payload, extent.id)
+		payload = append(payload, 0, 0, 0, 0)
+		payload = binary.BigEndian.AppendUint64(payload, baseOffset)
+		payload = binary.BigEndian.AppendUint16(payload, 1)
+		payload = binary.BigEndian.AppendUint64(payload, extent.offset)
+		payload = binary.BigEndian.AppendUint64(payload, extent.length)
+	}
+	return fullBox("iloc", 1, payload)
+}
+
+func testHEIC(iloc func(exifOffset uint64) []byte) ([]byte, int) {
+	ftyp := isoBox("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+	iinf := fullBox("iinf", 0, []byte{0, 2}, heicInfe(1, "hvc1"), heicInfe(2, "Exif"))
+	iprp := isoBox("iprp", isoBox("ipco", fullBox("ispe", 0, []byte{0, 0, 0, 64, 0, 0, 0, 48})))
+
+	// the location box has a fixed size, so it's built once to learn where mdat starts
+	build := func(exifOffset uint64) []byte {
+		meta := fullBox("meta", 0, iinf, iloc(exifOffset), iprp)
+		return append(bytes.Clone(ftyp), meta...)
+	}
+	head := build(0)
+	exifOffset := len(head) + 8
+
+	data := append(build(uint64(exifOffset)), isoBox("mdat", exifWithGPS)...)
+	return data, exifOffset
+}
+
+func TestSanitizeHEIC(t *testing.T) {
+	data, exifOffset := testHEIC(func(exifOffset uint64) []byte {
+		return heicIloc(0, testExtent{id: 2, offset: exifOffset, length: uint64(len(exifWithGPS))})
+	})
+
+	out, format, err := Sanitize(data, HEIC)
+	if err != nil {
+		t.Fatalf("Sanitize() error = %v", err)
+	}
+	if format != HEIC {
+		t.Errorf("Sanitize() format = %s, want %s", format, HEIC)
+	}
+	if len(out) != len(data) {
+		t.Fatalf("Sanitize() changed the size from %d to %d", len(data), len(out))
+	}
+	if !bytes.Equal(out[:exifOffset], data[:exifOffset]) {
+		t.Errorf("Sanitize() changed more than the exif item")
+	}
+	if !bytes.Equal(out[exifOffset:], make([]byte, len(exifWithGPS))) {
+		t.Errorf("Sanitize() kept the exif item")
+	}
+
+	info, err := Inspect(data)
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	if info.Width != 64 || info.Height != 48 {
+		t.Errorf("Inspect() = %dx%d, want 64x48", info.Width, info.Height)
+	}
+}
+
+func TestSanitizeHEICOutOfBounds(t *testing.T) {
+	tests := []struct {
+		name string
+		iloc func(exifOffset uint64) []byte
+	}{
+		{
+			name: "past the end",
+			iloc: func(exifOffset uint64) []byte {
+				return heicIloc(0, testExtent{id: 2, offset: exifOffset, length: 1 << 20})
+			},
+		},
+		{
+			name: "offset plus length wraps around",
+			iloc: func(uint64) []byte {
+				return heicIloc(0, testExtent{id: 2, offset: 0xffffffffffffffff, length: 2})
+			},
+		},
+		{
+			name: "base offset plus offset wraps around",
+			iloc: func(uint64) []byte {
+				return heicIloc(0xffffffffffffffff, testExtent{id: 2, offset: 2, length: 2})
+			},
+		},
+		{
+			name: "empty extent",
+			iloc: func(exifOffset uint64) []byte {
+				return heicIloc(0, testExtent{id: 2, offset: exifOffset, length: 0})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := testHEIC(tt.iloc)
+
+			_, _, err := Sanitize(data, HEIC)
+			if !errors.Is(err, ErrCorrupted) {
+				t.Errorf("Sanitize() error = %v, want %v", err, ErrCorrupted)
+			}
+		})
+	}
+}
+
+func TestSanitizeHEICTruncatedLocations(t *testing.T) {
+	data, _ := testHEIC(func(uint64) []byte {
+		return fullBox("iloc", 1, []byte{0x88, 0x80, 0, 5})
+	})
+
+	_, _, err := Sanitize(data, HEIC)
+	if !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Sanitize() error = %v, want %v", err, ErrCorrupted)
+	}
+}
+
+func FuzzSanitizeHEIC(f *testing.F) {
+	data, exifOffset := testHEIC(func(exifOffset uint64) []byte {
+		return heicIloc(0, testExtent{id: 2, offset: exifOffset, length: uint64(len(exifWithGPS))})
+	})
+	f.Add(data)
+	f.Add(data[:exifOffset])
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out, _, err := Sanitize(data, HEIC)
+		if err == nil && len(out) != len(data) {
+			t.Errorf("Sanitize() changed the size from %d to %d", len(data), len(out))
+		}
+
+		Inspect(data)
+	})
+}
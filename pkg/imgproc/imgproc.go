@@ -0,0 +1,67 @@
+package imgproc
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+type Limits struct {
+	MaxFileSize int64
+	MaxWidth    int
+	MaxHeight   int
+}
+
+type Info struct {
+	Format Format
+	Width  int
+	Height int
+}
+
+// Inspect detects the format of the image and reads its dimensions without
+// decoding the pixels.
+func Inspect(data []byte) (*Info, error) {
+	format, err := Detect(data)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{Format: format}
+	if format == HEIC {
+		info.Width, info.Height, err = heicDimensions(data)
+		if err != nil {
+			return nil, err
+		}
+		return info, nil
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+	info.Width, info.Height = cfg.Width, cfg.Height
+
+	return info, nil
+}
+
+// Validate inspects the image and checks it against the limits.
+func Validate(data []byte, limits Limits) (*Info, error) {
+	if int64(len(data)) > limits.MaxFileSize {
+		return nil, fmt.Errorf("%w: %d bytes, max %d", ErrFileTooLarge, len(data), limits.MaxFileSize)
+	}
+
+	info, err := Inspect(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Width <= 0 || info.Height <= 0 || info.Width > limits.MaxWidth || info.Height > limits.MaxHeight {
+		return nil, fmt.Errorf("%w: %dx%d, max %dx%d", ErrDimensions, info.Width, info.Height, limits.MaxWidth, limits.MaxHeight)
+	}
+
+	return info, nil
+}
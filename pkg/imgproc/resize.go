@@ -0,0 +1,79 @@
+package imgproc
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+const variantQuality = 82
+
+type Variant struct {
+	Width  int
+	Height int
+	Format Format
+	Data   []byte
+}
+
+func Decode(data []byte, format Format) (image.Image, error) {
+	var img image.Image
+	var err error
+
+	switch format {
+	case JPEG:
+		img, err = jpeg.Decode(bytes.NewReader(data))
+	case PNG:
+		img, err = png.Decode(bytes.NewReader(data))
+	case WebP:
+		img, err = webp.Decode(bytes.NewReader(data))
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+
+	return img, nil
+}
+
+// Variants scales the image down to each of the widths and encodes the
+// results as JPEG. Widths the original is not wider than are skipped, clients
+// fall back to the original for them.
+func Variants(data []byte, format Format, widths []int) ([]*Variant, error) {
+	src, err := Decode(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	variants := make([]*Variant, 0, len(widths))
+	for _, width := range widths {
+		if width <= 0 || width >= bounds.Dx() {
+			continue
+		}
+
+		height := max(1, bounds.Dy()*width/bounds.Dx())
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+
+		var buf bytes.Buffer
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: variantQuality})
+		if err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, &Variant{
+			Width:  width,
+			Height: height,
+			Format: JPEG,
+			Data:   buf.Bytes(),
+		})
+	}
+
+	return variants, nil
+}
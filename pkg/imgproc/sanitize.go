@@ -0,0 +1,265 @@
+package imgproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/webp"
+)
+
+const jpegQuality = 90
+
+// Sanitize removes metadata that can identify the user, GPS coordinates
+// first of all, and bakes the EXIF orientation into the pixels. Containers are
+// rewritten without re-encoding unless the image has to be rotated. Rotated
+// WebP images come back as JPEG because there is no WebP encoder at hand.
+func Sanitize(data []byte, format Format) ([]byte, Format, error) {
+	switch format {
+	case JPEG:
+		return sanitizeJPEG(data)
+	case PNG:
+		return sanitizePNG(data)
+	case WebP:
+		return sanitizeWebP(data)
+	case HEIC:
+		return sanitizeHEIC(data)
+	}
+
+	return nil, "", ErrUnsupportedFormat
+}
+
+// sanitizeJPEG filters the segments up to the end of image marker, including
+// those between the scans of progressive images. Whatever follows the end of
+// image is dropped: MPF secondary images, gain maps and vendor trailers are
+// JPEGs of their own and carry their own EXIF.
+func sanitizeJPEG(data []byte) ([]byte, Format, error) {
+	if !bytes.HasPrefix(data, []byte{0xff, 0xd8}) {
+		return nil, "", ErrCorrupted
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+
+	orientation := 1
+	pos := 2
+	for pos < len(data) {
+		for pos+1 < len(data) && data[pos] == 0xff && data[pos+1] == 0xff {
+			pos++
+		}
+		if pos+2 > len(data) || data[pos] != 0xff {
+			return nil, "", fmt.Errorf("%w: broken jpeg segment", ErrCorrupted)
+		}
+
+		marker := data[pos+1]
+		if marker == 0xd9 {
+			out.Write(data[pos : pos+2])
+			break
+		}
+		// restart markers and TEM stand alone
+		if marker >= 0xd0 && marker <= 0xd7 || marker == 0x01 {
+			out.Write(data[pos : pos+2])
+			pos += 2
+			continue
+		}
+
+		if pos+4 > len(data) {
+			return nil, "", fmt.Errorf("%w: broken jpeg segment", ErrCorrupted)
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, "", fmt.Errorf("%w: broken jpeg segment", ErrCorrupted)
+		}
+		payload := data[pos+4 : end]
+
+		// start of scan: the entropy coded data follows up to the next marker,
+		// there is nothing to strip in it
+		if marker == 0xda {
+			scanEnd := jpegScanEnd(data, end)
+			out.Write(data[pos:scanEnd])
+			pos = scanEnd
+			continue
+		}
+
+		if marker == 0xe1 && bytes.HasPrefix(payload, exifHeader) {
+			orientation = exifOrientation(payload)
+		}
+
+		if keepJPEGSegment(marker, payload) {
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	if orientation == 1 {
+		return out.Bytes(), JPEG, nil
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+
+	return encodeJPEG(orient(img, orientation))
+}
+
+// jpegScanEnd returns the position of the first marker after the entropy
+// coded data starting at pos. Stuffed zero bytes, fill bytes and restart
+// markers are part of the scan.
+func jpegScanEnd(data []byte, pos int) int {
+	for ; pos+1 < len(data); pos++ {
+		if data[pos] != 0xff {
+			continue
+		}
+
+		next := data[pos+1]
+		if next == 0x00 || next == 0xff || next >= 0xd0 && next <= 0xd7 {
+			continue
+		}
+		return pos
+	}
+
+	return len(data)
+}
+
+// keepJPEGSegment keeps everything needed to render the image: tables, frame
+// headers, JFIF, ICC profiles and the Adobe color transform marker.
+func keepJPEGSegment(marker byte, payload []byte) bool {
+	switch {
+	case marker == 0xe0, marker == 0xee:
+		return true
+	case marker == 0xe2:
+		return bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00"))
+	case marker >= 0xe1 && marker <= 0xef, marker == 0xfe:
+		return false
+	}
+	return true
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+func sanitizePNG(data []byte) ([]byte, Format, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, "", ErrCorrupted
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+
+	orientation := 1
+	pos := len(pngSignature)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, "", fmt.Errorf("%w: broken png chunk", ErrCorrupted)
+		}
+
+		if chunkType == "eXIf" {
+			orientation = exifOrientation(data[pos+8 : pos+8+length])
+		}
+
+		if !pngMetadataChunks[chunkType] {
+			out.Write(data[pos:end])
+		}
+		pos = end
+
+		if chunkType == "IEND" {
+			break
+		}
+	}
+
+	if orientation == 1 {
+		return out.Bytes(), PNG, nil
+	}
+
+	img, err := png.Decode(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+
+	var buf bytes.Buffer
+	err = png.Encode(&buf, orient(img, orientation))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), PNG, nil
+}
+
+const (
+	vp8xFlagXMP  = 0x04
+	vp8xFlagEXIF = 0x08
+)
+
+func sanitizeWebP(data []byte) ([]byte, Format, error) {
+	if len(data) < 12 {
+		return nil, "", ErrCorrupted
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+
+	orientation := 1
+	pos := 12
+	for pos+8 <= len(data) {
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		end := pos + 8 + size + size%2
+		if size < 0 || end > len(data) {
+			return nil, "", fmt.Errorf("%w: broken webp chunk", ErrCorrupted)
+		}
+
+		switch fourCC {
+		case "EXIF":
+			orientation = exifOrientation(data[pos+8 : pos+8+size])
+		case "XMP ":
+		case "VP8X":
+			chunk := bytes.Clone(data[pos:end])
+			if size > 0 {
+				chunk[8] &^= vp8xFlagEXIF | vp8xFlagXMP
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	result := out.Bytes()
+	binary.LittleEndian.PutUint32(result[4:8], uint32(len(result)-8))
+
+	if orientation == 1 {
+		return result, WebP, nil
+	}
+
+	img, err := webp.Decode(bytes.NewReader(result))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+
+	return encodeJPEG(orient(img, orientation))
+}
+
+func encodeJPEG(img image.Image) ([]byte, Format, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), JPEG, nil
+}
@@ -0,0 +1,334 @@
+package imgproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// exifWithGPS is an EXIF block with a GPS IFD pointer, which is what must never
+// reach other users.
+var exifWithGPS = append(bytes.Clone(exifHeader), []byte("MM\x00*\x00\x00\x00\x08\x00\x01\x88\x25\x00\x04\x00\x00\x00\x01\x00\x00\x00\x1a\x00\x00\x00\x00GPS")...)
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 32), 0, 0xff})
+		}
+	}
+	return img
+}
+
+func testJPEG(t testing.TB) []byte {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, testImage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testPNG(t testing.TB) []byte {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, testImage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// withJPEGSegment puts the segment right after SOI.
+func withJPEGSegment(data []byte, segment []byte) []byte {
+	out := bytes.Clone(data[:2])
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+// beforeEOI puts the bytes between the last scan and EOI.
+func beforeEOI(data []byte, extra []byte) []byte {
+	out := bytes.Clone(data[:len(data)-2])
+	out = append(out, extra...)
+	return append(out, 0xff, 0xd9)
+}
+
+func pngChunk(chunkType string, payload []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, payload...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// withPNGChunk puts the chunk right after IHDR.
+func withPNGChunk(data []byte, chunk []byte) []byte {
+	ihdrEnd := len(pngSignature) + 12 + 13
+	out := bytes.Clone(data[:ihdrEnd])
+	out = append(out, chunk...)
+	return append(out, data[ihdrEnd:]...)
+}
+
+func webpChunk(fourCC string, payload []byte) []byte {
+	chunk := append([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))...)
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func testWebP(chunks ...[]byte) []byte {
+	data := []byte("RIFF\x00\x00\x00\x00WEBP")
+	for _, chunk := range chunks {
+		data = append(data, chunk...)
+	}
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+	return data
+}
+
+func TestSanitizeJPEG(t *testing.T) {
+	base := testJPEG(t)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "exif segment",
+			data: withJPEGSegment(base, jpegSegment(0xe1, exifWithGPS)),
+		},
+		{
+			name: "xmp and comment",
+			data: withJPEGSegment(withJPEGSegment(base, jpegSegment(0xfe, []byte("GPS comment"))), jpegSegment(0xe1, []byte("http://ns.adobe.com/xap/1.0/\x00GPS"))),
+		},
+		{
+			name: "segments after the scan",
+			data: beforeEOI(base, append(jpegSegment(0xe1, exifWithGPS), jpegSegment(0xfe, []byte("GPS"))...)),
+		},
+		{
+			name: "secondary image after EOI",
+			data: append(bytes.Clone(base), withJPEGSegment(testJPEG(t), jpegSegment(0xe1, exifWithGPS))...),
+		},
+		{
+			name: "vendor trailer after EOI",
+			data: append(bytes.Clone(base), []byte("GPS trailer")...),
+		},
+		{
+			name: "mpf index",
+			data: withJPEGSegment(base, jpegSegment(0xe2, []byte("MPF\x00GPS"))),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, format, err := Sanitize(tt.data, JPEG)
+			if err != nil {
+				t.Fatalf("Sanitize() error = %v", err)
+			}
+			if format != JPEG {
+				t.Errorf("Sanitize() format = %s, want %s", format, JPEG)
+			}
+			if bytes.Contains(out, []byte("GPS")) {
+				t.Errorf("Sanitize() kept metadata")
+			}
+			if !bytes.HasSuffix(out, []byte{0xff, 0xd9}) {
+				t.Errorf("Sanitize() output doesn't end with EOI")
+			}
+
+			_, err = jpeg.Decode(bytes.NewReader(out))
+			if err != nil {
+				t.Errorf("sanitized jpeg doesn't decode: %v", err)
+			}
+		})
+	}
+}
+
+func TestSanitizeJPEGKeepsImage(t *testing.T) {
+	base := testJPEG(t)
+	icc := jpegSegment(0xe2, []byte("ICC_PROFILE\x00\x01\x01profile"))
+	data := withJPEGSegment(base, icc)
+
+	out, _, err := Sanitize(data, JPEG)
+	if err != nil {
+		t.Fatalf("Sanitize() error = %v", err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Errorf("Sanitize() changed a jpeg without metadata")
+	}
+}
+
+func TestSanitizeJPEGOrientation(t *testing.T) {
+	exif := append(bytes.Clone(exifHeader), []byte("MM\x00*\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x06\x00\x00\x00\x00\x00\x00")...)
+	data := withJPEGSegment(testJPEG(t), jpegSegment(0xe1, exif))
+
+	out, _, err := Sanitize(data, JPEG)
+	if err != nil {
+		t.Fatalf("Sanitize() error = %v", err)
+	}
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 8 || cfg.Height != 16 {
+		t.Errorf("rotated size = %dx%d, want 8x16", cfg.Width, cfg.Height)
+	}
+}
+
+func TestSanitizeJPEGCorrupted(t *testing.T) {
+	base := testJPEG(t)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "soi only", data: []byte{0xff, 0xd8, 0xff}},
+		{name: "segment past the end", data: append([]byte{0xff, 0xd8}, 0xff, 0xe1, 0xff, 0xff, 0x00)},
+		{name: "short segment length", data: append([]byte{0xff, 0xd8}, 0xff, 0xe1, 0x00, 0x01)},
+		{name: "garbage between segments", data: append(bytes.Clone(base[:2]), append([]byte{0x00}, base[2:]...)...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Sanitize(tt.data, JPEG)
+			if !errors.Is(err, ErrCorrupted) {
+				t.Errorf("Sanitize() error = %v, want %v", err, ErrCorrupted)
+			}
+		})
+	}
+}
+
+func TestSanitizePNG(t *testing.T) {
+	base := testPNG(t)
+
+	tests := []struct {
+		name  string
+		chunk []byte
+	}{
+		{name: "exif", chunk: pngChunk("eXIf", exifWithGPS[len(exifHeader):])},
+		{name: "text", chunk: pngChunk("tEXt", []byte("Location\x00GPS"))},
+		{name: "international text", chunk: pngChunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00GPS"))},
+		{name: "time", chunk: pngChunk("tIME", []byte("GPS\x00\x00\x00\x00"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, format, err := Sanitize(withPNGChunk(base, tt.chunk), PNG)
+			if err != nil {
+				t.Fatalf("Sanitize() error = %v", err)
+			}
+			if format != PNG {
+				t.Errorf("Sanitize() format = %s, want %s", format, PNG)
+			}
+			if !bytes.Equal(out, base) {
+				t.Errorf("Sanitize() didn't drop the chunk")
+			}
+		})
+	}
+}
+
+func TestSanitizePNGCorrupted(t *testing.T) {
+	base := testPNG(t)
+
+	broken := bytes.Clone(base)
+	binary.BigEndian.PutUint32(broken[len(pngSignature):], 0xffffffff)
+
+	for name, data := range map[string][]byte{
+		"not a png":     []byte("GIF89a"),
+		"chunk too big": broken,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := Sanitize(data, PNG)
+			if !errors.Is(err, ErrCorrupted) {
+				t.Errorf("Sanitize() error = %v, want %v", err, ErrCorrupted)
+			}
+		})
+	}
+}
+
+func TestSanitizeWebP(t *testing.T) {
+	vp8x := []byte{vp8xFlagEXIF | vp8xFlagXMP, 0, 0, 0, 15, 0, 0, 7, 0, 0}
+	bitstream := webpChunk("VP8L", []byte{0x2f, 0x0f, 0xc0, 0x01})
+
+	data := testWebP(
+		webpChunk("VP8X", vp8x),
+		bitstream,
+		webpChunk("EXIF", exifWithGPS[len(exifHeader):]),
+		webpChunk("XMP ", []byte("<x:xmpmeta>GPS</x:xmpmeta>")),
+	)
+
+	out, format, err := Sanitize(data, WebP)
+	if err != nil {
+		t.Fatalf("Sanitize() error = %v", err)
+	}
+	if format != WebP {
+		t.Errorf("Sanitize() format = %s, want %s", format, WebP)
+	}
+
+	want := testWebP(webpChunk("VP8X", append([]byte{0}, vp8x[1:]...)), bitstream)
+	if !bytes.Equal(out, want) {
+		t.Errorf("Sanitize() = %x, want %x", out, want)
+	}
+}
+
+func TestSanitizeWebPCorrupted(t *testing.T) {
+	data := testWebP(webpChunk("VP8L", []byte{1, 2, 3, 4}))
+	binary.LittleEndian.PutUint32(data[16:20], 0x7fffffff)
+
+	_, _, err := Sanitize(data, WebP)
+	if !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Sanitize() error = %v, want %v", err, ErrCorrupted)
+	}
+}
+
+func FuzzSanitizeJPEG(f *testing.F) {
+	base := testJPEG(f)
+	f.Add(base)
+	f.Add(withJPEGSegment(base, jpegSegment(0xe1, exifWithGPS)))
+	f.Add(beforeEOI(base, jpegSegment(0xfe, []byte("comment"))))
+	f.Add(append(bytes.Clone(base), base...))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out, _, err := Sanitize(data, JPEG)
+		if err == nil && !bytes.HasPrefix(out, []byte{0xff, 0xd8}) {
+			t.Errorf("Sanitize() output lost the start of image")
+		}
+	})
+}
+
+func FuzzSanitizePNG(f *testing.F) {
+	base := testPNG(f)
+	f.Add(base)
+	f.Add(withPNGChunk(base, pngChunk("eXIf", exifWithGPS[len(exifHeader):])))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out, _, err := Sanitize(data, PNG)
+		if err == nil && !bytes.HasPrefix(out, pngSignature) {
+			t.Errorf("Sanitize() output lost the png signature")
+		}
+	})
+}
+
+func FuzzSanitizeWebP(f *testing.F) {
+	f.Add(testWebP(webpChunk("VP8X", make([]byte, 10)), webpChunk("EXIF", exifWithGPS[len(exifHeader):])))
+	f.Add(testWebP(webpChunk("VP8L", []byte{0x2f, 0x0f, 0xc0, 0x01})))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out, format, err := Sanitize(data, WebP)
+		if err == nil && format == WebP && int(binary.LittleEndian.Uint32(out[4:8])) != len(out)-8 {
+			t.Errorf("Sanitize() left a wrong riff size")
+		}
+	})
+}
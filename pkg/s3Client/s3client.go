@@ -6,19 +6,42 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
-func NewClient(ctx context.Context) (*s3.Client, error) {
+// S3Config overrides the default aws configuration. Empty fields keep the
+// defaults, which lets the same build talk to Yandex Cloud, MinIO or any other
+// S3 compatible storage.
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	UsePathStyle    bool
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+func NewClient(ctx context.Context, s3Cfg *S3Config) (*s3.Client, error) {
 	cfg, err := config.LoadDefaultConfig(ctx, func(lo *config.LoadOptions) error {
 		lo.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		if s3Cfg.Region != "" {
+			lo.Region = s3Cfg.Region
+		}
+		if s3Cfg.AccessKeyID != "" {
+			lo.Credentials = credentials.NewStaticCredentialsProvider(s3Cfg.AccessKeyID, s3Cfg.SecretAccessKey, "")
+		}
 		return nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to load default s3 config, err: %w", err)
 	}
 
-	client := s3.NewFromConfig(cfg)
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if s3Cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(s3Cfg.Endpoint)
+		}
+		o.UsePathStyle = s3Cfg.UsePathStyle
+	})
 
 	return client, nil
 }